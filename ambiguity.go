@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// wordCodes are ISO 4217 codes that are also English words, so "convert
+// all 100 USD to EUR" need not be about the Albanian lek.
+var wordCodes = []string{"ALL", "BAM", "CUP", "GEL", "MAD", "MOP", "PEN", "SOS", "TOP", "TRY"}
+
+// wordNames are currency names that are also English words, so "I won 100
+// USD" need not be about the Korean won.
+var wordNames = []string{"won", "real", "colon"}
+
+// determinerWords next to a word show it is being used as English, as in
+// "all of my" or "the top".
+var determinerWords = []string{"A", "AN", "THE", "OF", "MY", "YOUR", "OUR", "THIS", "THAT", "THESE", "THOSE", "IT"}
+
+// Kinds of AmbiguityError.
+const (
+	AmbiguousCurrency = "currency"
+	AmbiguousAmount   = "amount"
+	AmbiguousDate     = "date"
+)
+
+// AmbiguityError asks which currency a word in a query means, or which of
+// its readings an amount such as "1,234" or a date such as "05/01/2022"
+// has. Optional is set when the word may not be a currency at all, as
+// "all" in "convert all 100 USD to EUR".
+type AmbiguityError struct {
+	Kind       string
+	Pos        int
+	Text       string
+	Candidates []string
+	Optional   bool
+}
+
+func (err *AmbiguityError) Error() string {
+	return fmt.Sprintf("%s (%q at column %d)", err.Question(), err.Text, err.Pos+1)
+}
+
+// Question is the clarification to put to the user.
+func (err *AmbiguityError) Question() string {
+	if err.Optional && len(err.Candidates) == 1 {
+		return fmt.Sprintf("Did you mean %q as the currency %s (%s)?", err.Text, err.Candidates[0], currencyName(err.Candidates[0]))
+	}
+	return fmt.Sprintf("Did you mean %s?", joinAlternatives(err.Candidates))
+}
+
+func joinAlternatives(codes []string) string {
+	if len(codes) < 2 {
+		return strings.Join(codes, "")
+	}
+	return strings.Join(codes[:len(codes)-1], ", ") + " or " + codes[len(codes)-1]
+}
+
+func currencyName(code string) string {
+	if currency, ok := lookupCurrency(code); ok {
+		return currency.Name
+	}
+	return code
+}
+
+func isWordCode(code string) bool {
+	return slices.Contains(wordCodes, code)
+}
+
+// isWordCurrency reports whether match could as well be an English word,
+// and so is only a currency where readsAsCurrency says it is.
+func isWordCurrency(match CurrencyMatch) bool {
+	switch match.Kind {
+	case VocabularyCode:
+		return isWordCode(match.Code)
+	case VocabularyName:
+		return slices.Contains(wordNames, strings.ToLower(match.Text))
+	}
+	return false
+}
+
+// readsAsCurrency decides whether the word code or name at pos is meant as
+// a currency. The words around it decide first: an amount or a connector
+// before it, or a connector after it, make it a currency, while an amount
+// and currency after it, another currency straight after it, or a
+// determiner beside it make it an English word. When that settles nothing,
+// a query that mixes cases is taken at its word, so "ALL" is the lek and
+// "all" is not. decided is false when neither helps.
+func readsAsCurrency(tokens []Token, pos int) (currency, decided bool) {
+	asCurrency, asWord := false, false
+
+	if pos > 0 {
+		previous := tokens[pos-1]
+		switch {
+		case previous.Kind == TokenNumber || previous.Kind == TokenComma:
+			asCurrency = true
+		case previous.Kind == TokenWord && isLinkWord(previous.word()):
+			asCurrency = true
+		case previous.Kind == TokenWord && slices.Contains(determinerWords, previous.word()):
+			asWord = true
+		}
+	}
+
+	if pos+1 < len(tokens) {
+		next := tokens[pos+1]
+		switch {
+		case next.Kind == TokenNumber:
+			if pos+2 < len(tokens) && startsCurrency(tokens, pos+2) {
+				asWord = true
+			} else {
+				asCurrency = true
+			}
+		case next.Kind == TokenWord && slices.Contains(connectorWords, next.word()):
+			asCurrency = true
+		case next.Kind == TokenWord && slices.Contains(determinerWords, next.word()):
+			asWord = true
+		case startsCurrency(tokens, pos+1):
+			asWord = true
+		}
+	}
+
+	if asCurrency != asWord {
+		return asCurrency, true
+	}
+	if mixedCase(tokens) {
+		return isUpper(tokens[pos].Text), true
+	}
+	return false, false
+}
+
+func startsCurrency(tokens []Token, pos int) bool {
+	_, ok := matchCurrency(tokens, pos)
+	return ok
+}
+
+// mixedCase reports whether the query's words are written in both upper
+// and lower case, which is when the case of a single word says something.
+func mixedCase(tokens []Token) bool {
+	upper, lower := false, false
+	for _, token := range tokens {
+		if token.Kind != TokenWord {
+			continue
+		}
+		if isUpper(token.Text) {
+			upper = true
+		} else {
+			lower = true
+		}
+	}
+	return upper && lower
+}
+
+func isUpper(text string) bool {
+	return strings.ToUpper(text) == text && strings.IndexFunc(text, unicode.IsLetter) >= 0
+}
@@ -61,17 +61,29 @@ import (
 	"time"
 )
 
-func useApi(date string) (Latest, error) {
+// errNoRatesForDay is returned when the provider has no rates for the day
+// asked for, as opposed to refusing or failing the request.
+var errNoRatesForDay = errors.New("no rates published for that day")
+
+// apiError is the body the provider answers a failed request with.
+type apiError struct {
+	Status      int    `json:"status"`
+	Message     string `json:"message"`
+	Description string `json:"description"`
+}
+
+func useApi(date string) (RateSnapshot, error) {
 	var apiEndPoint string
 	var appID string = getEnvVar("APP_ID")
 	var result Latest
+	var snapshot RateSnapshot
 
 	cacheKey := fmt.Sprintf("exchange_rates:%s", date)
 
 	cachedData, err := getFromCache(cacheKey)
 	if err == nil {
 		log.Printf("Data found in Redis cache for date: %s\n", date)
-		return cachedData, nil
+		return snapshotFromEnvelope(cachedData, CacheLayerRedis), nil
 	} else {
 		log.Printf("Data not found in Redis cache for date: %s. Error: %v\n", date, err)
 	}
@@ -87,23 +99,41 @@ func useApi(date string) (Latest, error) {
 	response, err := http.Get(apiEndPoint)
 	if err != nil {
 		log.Printf("API request failed: %v\n", err)
-		return result, fmt.Errorf("get request failed: %w", err)
+		return snapshot, fmt.Errorf("get request failed: %w", err)
 	}
 	defer response.Body.Close()
 
 	responseData, err := io.ReadAll(response.Body)
 	if err != nil {
 		log.Printf("Failed to read response body: %v\n", err)
-		return result, fmt.Errorf("failed to read response body: %w", err)
+		return snapshot, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		var failure apiError
+		json.Unmarshal(responseData, &failure)
+		log.Printf("API request refused: %d %s\n", response.StatusCode, failure.Message)
+		if failure.Message == "not_available" {
+			return snapshot, fmt.Errorf("%s: %w", date, errNoRatesForDay)
+		}
+		detail := failure.Description
+		if detail == "" {
+			detail = http.StatusText(response.StatusCode)
+		}
+		return snapshot, fmt.Errorf("rates request refused with status %d: %s", response.StatusCode, detail)
 	}
 
 	err = json.Unmarshal(responseData, &result)
 	if err != nil {
 		log.Printf("Failed to parse JSON response: %v\n", err)
-		return result, fmt.Errorf("failed to parse JSON response: %w", err)
+		return snapshot, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	if len(result.Rates) == 0 {
+		return snapshot, fmt.Errorf("%s: %w", date, errNoRatesForDay)
 	}
 
-	err = setToCache(cacheKey, result, 24*time.Hour)
+	envelope := newCacheEnvelope(result, defaultProvider, time.Now().Unix())
+	err = setToCache(cacheKey, envelope, 24*time.Hour)
 	if err != nil {
 		log.Printf("Failed to cache data in Redis: %v\n", err)
 	} else {
@@ -111,14 +141,17 @@ func useApi(date string) (Latest, error) {
 	}
 
 	log.Println("Successfully fetched and processed exchange rates")
-	return result, nil
+	return snapshotFromEnvelope(envelope, CacheLayerAPI), nil
 }
 
-func getHistoricalRate(date string) (Latest, error) {
-	_, err := time.Parse("2006-01-02", date)
+func getHistoricalRate(date string) (RateSnapshot, error) {
+	parsed, err := time.Parse("2006-01-02", date)
 	if err != nil {
 		log.Printf("Invalid date format provided: %s\n", date)
-		return Latest{}, errors.New("invalid date format. please use YYYY-MM-DD")
+		return RateSnapshot{}, errors.New("invalid date format. please use YYYY-MM-DD")
+	}
+	if err := validateRateDate(parsed, time.Now()); err != nil {
+		return RateSnapshot{}, err
 	}
 
 	log.Printf("Fetching historical rates for date: %s\n", date)
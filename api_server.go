@@ -1,7 +1,12 @@
 package main
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -9,19 +14,160 @@ import (
 )
 
 type ConversionRequest struct {
-	From   string  `json:"from" query:"from"`
-	To     string  `json:"to" query:"to"`
-	Amount float64 `json:"amount" query:"amount"`
-	Date   string  `json:"date" query:"date"`
+	// Query is a natural-language query such as "100 USD to EUR on
+	// 2023-06-30". It fills in any of the other fields left empty.
+	Query string `json:"q" query:"q"`
+
+	// Locale, such as "en-CA", settles symbols like "$" in Query that
+	// several currencies share. It defaults to LOCALE.
+	Locale string `json:"locale" query:"locale"`
+
+	From   string `json:"from" query:"from"`
+	To     string `json:"to" query:"to"`
+	Amount string `json:"amount" query:"amount"`
+	Date   string `json:"date" query:"date"`
+
+	// Start and End ask for a daily series over a range of days instead of
+	// a single conversion.
+	Start string `json:"start" query:"start"`
+	End   string `json:"end" query:"end"`
+
+	Rounding string `json:"rounding" query:"rounding"`
+	Profile  string `json:"profile" query:"profile"`
+	Overlay  string `json:"overlay" query:"overlay"`
+
+	// Unit and UnitTo weigh precious metal amounts in g, kg, ozt or tola.
+	Unit   string `json:"unit" query:"unit"`
+	UnitTo string `json:"to_unit" query:"to_unit"`
+
+	// TargetAmount asks for the amount of From needed to receive this much
+	// of To after fees, instead of converting Amount.
+	TargetAmount string `json:"target_amount" query:"target_amount"`
+
+	// Total asks for the results of a list or chain in Query to be added
+	// up by currency.
+	Total bool `json:"total" query:"total"`
+
+	// interpretations records how Query's symbols and names were read, and
+	// sources and chain the amounts it lists and the currencies it
+	// converts on to.
+	interpretations []Interpretation
+	sources         []QuerySource
+	chain           []string
+}
+
+// applyQuery fills the fields the caller left empty from a parsed query, so
+// explicit parameters take precedence over the query text.
+func (req *ConversionRequest) applyQuery(query Query) {
+	setDefault := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+
+	req.interpretations = query.Interpretations
+	req.sources = query.Sources
+	req.chain = query.Chain
+	req.Total = req.Total || query.Total
+	setDefault(&req.From, query.Source)
+	setDefault(&req.To, strings.Join(query.Targets, ","))
+	setDefault(&req.Unit, query.Unit)
+	setDefault(&req.UnitTo, query.UnitTo)
+	setDefault(&req.Date, query.Date)
+	setDefault(&req.Start, query.Period.Start)
+	setDefault(&req.End, query.Period.End)
+	setDefault(&req.Rounding, query.Modifiers["rounding"])
+	setDefault(&req.Profile, query.Modifiers["profile"])
+	setDefault(&req.Overlay, query.Modifiers["overlay"])
+
+	if query.Amount != nil && req.Amount == "" && req.TargetAmount == "" {
+		amount := Money{Amount: query.Amount, Scale: query.Scale}.String()
+		if query.Intent == IntentReverse {
+			req.TargetAmount = amount
+		} else {
+			req.Amount = amount
+		}
+	}
+}
+
+// targets splits To, which may list several currencies as "EUR,GBP,JPY".
+// Empty entries, as in "EUR,,GBP", are skipped.
+func (req *ConversionRequest) targets() []string {
+	var targets []string
+	for _, target := range strings.Split(req.To, ",") {
+		target = strings.TrimSpace(target)
+		if target != "" && !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// requestedUnit reads the unit or to_unit parameter, which may be left
+// empty.
+func requestedUnit(unit string) (string, bool) {
+	if unit == "" {
+		return "", true
+	}
+	return parseMassUnit(unit)
+}
+
+// BatchResponse answers a query that lists several amounts or chains
+// conversions, with every conversion in the order it was made.
+type BatchResponse struct {
+	Date      string               `json:"date"`
+	Timestamp time.Time            `json:"timestamp"`
+	Results   []ConversionResponse `json:"results"`
+	Totals    []BatchTotal         `json:"totals,omitempty"`
+
+	Interpretations []Interpretation `json:"interpretations,omitempty"`
 }
 
 type ConversionResponse struct {
-	From      string    `json:"from"`
-	To        string    `json:"to"`
-	Amount    float64   `json:"amount"`
-	Result    float64   `json:"result"`
-	Date      string    `json:"date"`
-	Timestamp time.Time `json:"timestamp"`
+	From          string     `json:"from"`
+	Unit          string     `json:"unit,omitempty"`
+	To            string     `json:"to"`
+	UnitTo        string     `json:"to_unit,omitempty"`
+	Amount        string     `json:"amount"`
+	TargetAmount  string     `json:"target_amount,omitempty"`
+	Result        string     `json:"result"`
+	Rounding      Rounding   `json:"rounding"`
+	Profile       string     `json:"profile,omitempty"`
+	Overlay       string     `json:"overlay,omitempty"`
+	Route         string     `json:"route,omitempty"`
+	MidRate       string     `json:"mid_rate"`
+	EffectiveRate string     `json:"effective_rate"`
+	Fees          string     `json:"fees"`
+	Net           string     `json:"net"`
+	Provenance    Provenance `json:"provenance"`
+	Date          string     `json:"date"`
+	Timestamp     time.Time  `json:"timestamp"`
+
+	Interpretations []Interpretation `json:"interpretations,omitempty"`
+
+	net Money
+}
+
+// MultiConversionResponse answers a request with several target currencies.
+// Targets that could not be converted are listed under Errors, and the
+// others are still answered under Results.
+type MultiConversionResponse struct {
+	From      string               `json:"from"`
+	Amount    string               `json:"amount"`
+	Date      string               `json:"date"`
+	Timestamp time.Time            `json:"timestamp"`
+	Results   []ConversionResponse `json:"results"`
+	Errors    []TargetError        `json:"errors,omitempty"`
+
+	Interpretations []Interpretation `json:"interpretations,omitempty"`
+}
+
+// TargetError explains why one target of a request with several could not
+// be converted. Code is that of a ConversionError, when it was one.
+type TargetError struct {
+	To    string `json:"to"`
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
 }
 
 // func startAPIServer() {
@@ -47,6 +193,13 @@ func startAPIServer() {
 	e.GET("/", handleRoot)
 	e.GET("/convert", handleConversion)
 	e.GET("/rates", handleGetRates)
+	e.GET("/currencies", handleGetCurrencies)
+	e.GET("/pairs", handleGetPairs)
+	e.GET("/baskets", handleGetBaskets)
+	e.GET("/overlays", handleListOverlays)
+	e.GET("/overlays/:name", handleGetOverlay)
+	e.PUT("/overlays/:name", handlePutOverlay, requireOverlayToken)
+	e.DELETE("/overlays/:name", handleDeleteOverlay, requireOverlayToken)
 
 	// Handle 404 Not Found
 	e.Any("*", handle404)
@@ -60,8 +213,19 @@ func handleRoot(c echo.Context) error {
 		"message": "Welcome to the Currency Converter API",
 		"version": "1.0",
 		"endpoints": `
-			GET /convert?from=USD&to=EUR&amount=100&date=2023-06-30
+			GET /convert?from=USD&to=EUR&amount=100&date=2023-06-30&rounding=half-even&profile=bank
+			GET /convert?from=USD&to=EUR,GBP,JPY&amount=100 (several targets answer with one result each under "results")
+			GET /convert?q=how much is 100 JPY in GBP on 2023-06-30
+			GET /convert?q=10 EUR, 20 GBP and 3000 JPY to USD&total=true
+			GET /convert?from=XAU&unit=g&to=EUR&amount=250
+			GET /convert?from=USD&to=EUR&target_amount=500&profile=bank
 			GET /rates?date=2023-06-30
+			GET /currencies
+			GET /pairs
+			GET /baskets
+			GET /convert?from=SDRB&to=EUR&amount=1000&date=2023-06-30
+			GET /overlays
+			PUT /overlays/budget-2024 (Authorization: Bearer $OVERLAY_ADMIN_TOKEN)
 		`,
 	})
 }
@@ -79,7 +243,67 @@ func handleConversion(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
 	}
 
-	var rates map[string]float64
+	if req.Query != "" {
+		options := defaultParseOptions()
+		if req.Locale != "" {
+			options.Locale = parseLocale(req.Locale)
+		}
+		query, err := parseQueryWith(req.Query, options)
+		if err != nil {
+			return handleParseError(c, err)
+		}
+		req.applyQuery(query)
+	}
+
+	targets := req.targets()
+	if len(targets) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "to needs at least one target currency", "code": "missing_target"})
+	}
+	inputData := DataInput{Targets: targets, Date: req.Date, Profile: req.Profile, Overlay: req.Overlay}
+
+	if req.TargetAmount != "" {
+		if req.Amount != "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "amount and target_amount cannot both be set", "code": "conflicting_amounts"})
+		}
+		if len(targets) > 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "target_amount needs a single target currency", "code": "conflicting_amounts"})
+		}
+		target, err := parseMoney(req.TargetAmount, targets[0])
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error(), "code": "invalid_amount"})
+		}
+		inputData.Value = Money{Currency: req.From}
+		inputData.Target = target
+	} else {
+		if req.Amount == "" {
+			req.Amount = "1"
+		}
+		amount, err := parseMoney(req.Amount, req.From)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error(), "code": "invalid_amount"})
+		}
+		inputData.Value = amount
+	}
+
+	var ok bool
+	if inputData.Unit, ok = requestedUnit(req.Unit); !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown unit %q", req.Unit), "code": "invalid_unit"})
+	}
+	if inputData.UnitTo, ok = requestedUnit(req.UnitTo); !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown to_unit %q", req.UnitTo), "code": "invalid_unit"})
+	}
+
+	if req.Rounding != "" {
+		if _, err := parseRoundingMode(req.Rounding); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error(), "code": "invalid_rounding"})
+		}
+	}
+
+	if req.Start != "" || req.End != "" {
+		return handleSeries(c, req, inputData)
+	}
+
+	var snapshot RateSnapshot
 	var err error
 
 	if req.Date != "" {
@@ -87,39 +311,276 @@ func handleConversion(c echo.Context) error {
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
-		rates = castRateFromLatest(historicalData)
+		snapshot = historicalData
 	} else {
 		now := time.Now().Unix()
-		rates, err = caller(now)
+		snapshot, err = caller(now)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get exchange rates"})
 		}
 	}
 
-	inputData := DataInput{
-		Value:        req.Amount,
-		CurrencyFrom: req.From,
-		CurrencyTo:   req.To,
+	if len(req.sources) > 0 || len(req.chain) > 0 {
+		return handleBatch(c, req, inputData, snapshot)
 	}
 
-	result := convert(inputData, rates)
+	responses, failures, err := convertTargets(inputData, req, snapshot)
+	if len(responses) == 0 {
+		return handleConversionError(c, err)
+	}
+
+	if len(targets) == 1 {
+		responses[0].Interpretations = req.interpretations
+		return c.JSON(http.StatusOK, responses[0])
+	}
+
+	return c.JSON(http.StatusOK, MultiConversionResponse{
+		From:            req.From,
+		Amount:          inputData.Value.String(),
+		Date:            req.Date,
+		Timestamp:       time.Now(),
+		Results:         responses,
+		Errors:          failures,
+		Interpretations: req.interpretations,
+	})
+}
 
-	response := ConversionResponse{
-		From:      req.From,
-		To:        req.To,
-		Amount:    req.Amount,
-		Result:    result,
-		Date:      req.Date,
-		Timestamp: time.Now(),
+// convertTargets converts inputData to each of its targets. All targets are
+// converted from the one rate table, so the results are consistent with
+// each other. A target that fails is reported among the TargetErrors and
+// the rest are still converted; err is the first failure, for when none
+// succeeded.
+func convertTargets(inputData DataInput, req *ConversionRequest, snapshot RateSnapshot) ([]ConversionResponse, []TargetError, error) {
+	var responses []ConversionResponse
+	var failures []TargetError
+	var first error
+	for _, target := range inputData.Targets {
+		response, err := conversionResponse(inputData.withTarget(target), req, snapshot)
+		if err != nil {
+			failure := TargetError{To: target, Error: err.Error()}
+			var conversionErr *ConversionError
+			if errors.As(err, &conversionErr) {
+				failure.Code = conversionErr.Code()
+			}
+			failures = append(failures, failure)
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		responses = append(responses, response)
 	}
+	return responses, failures, first
+}
 
+// handleBatch answers a query with a list of amounts or a chain of
+// conversions, all made from the one rate table.
+func handleBatch(c echo.Context, req *ConversionRequest, inputData DataInput, snapshot RateSnapshot) error {
+	if inputData.isReverse() {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "target_amount cannot be used with a list or chain of conversions", "code": "conflicting_amounts"})
+	}
+
+	var responses []ConversionResponse
+	var failure error
+	totals := runBatch(listInputs(inputData, req.sources), req.chain, func(step DataInput) (Money, error) {
+		response, err := conversionResponse(step, req, snapshot)
+		if err != nil {
+			return Money{}, err
+		}
+		responses = append(responses, response)
+		return response.net, nil
+	}, func(step DataInput, err error) {
+		if failure == nil {
+			failure = err
+		}
+	})
+	if failure != nil {
+		return handleConversionError(c, failure)
+	}
+
+	response := BatchResponse{Date: req.Date, Timestamp: time.Now(), Results: responses, Interpretations: req.interpretations}
+	if req.Total {
+		response.Totals = totals
+	}
 	return c.JSON(http.StatusOK, response)
 }
 
+// MultiSeriesResponse holds the series for each target of a range query
+// over several currencies.
+type MultiSeriesResponse struct {
+	From    string       `json:"from"`
+	Start   string       `json:"start"`
+	End     string       `json:"end"`
+	Results []RateSeries `json:"results"`
+
+	Interpretations []Interpretation `json:"interpretations,omitempty"`
+}
+
+// SeriesResponse is a single target's series.
+type SeriesResponse struct {
+	RateSeries
+
+	Interpretations []Interpretation `json:"interpretations,omitempty"`
+}
+
+// handleSeries answers a range query with the daily rate to each target
+// and a summary of how it moved.
+func handleSeries(c echo.Context, req *ConversionRequest, inputData DataInput) error {
+	if req.Start == "" || req.End == "" || req.Date != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "a range needs both start and end, and no date", "code": "invalid_range"})
+	}
+	if inputData.isReverse() {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "target_amount cannot be used with a range", "code": "invalid_range"})
+	}
+	period := DateRange{Start: req.Start, End: req.End}
+	for _, date := range []string{period.Start, period.End} {
+		parsed, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid date %q", date), "code": "invalid_range"})
+		}
+		if err := validateRateDate(parsed, time.Now()); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error(), "code": "invalid_range"})
+		}
+	}
+	if _, err := period.days(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error(), "code": "invalid_range"})
+	}
+	inputData.Rounding.Mode = RoundingMode(req.Rounding)
+
+	results, err := rateSeries(inputData, period, getHistoricalRate)
+	if err != nil {
+		return handleConversionError(c, err)
+	}
+
+	if len(results) == 1 {
+		return c.JSON(http.StatusOK, SeriesResponse{RateSeries: results[0], Interpretations: req.interpretations})
+	}
+	return c.JSON(http.StatusOK, MultiSeriesResponse{
+		From:            req.From,
+		Start:           period.Start,
+		End:             period.End,
+		Results:         results,
+		Interpretations: req.interpretations,
+	})
+}
+
+func conversionResponse(inputData DataInput, req *ConversionRequest, snapshot RateSnapshot) (ConversionResponse, error) {
+	rounding, err := resolveRounding(inputData.CurrencyTo, req.Rounding)
+	if err != nil {
+		return ConversionResponse{}, err
+	}
+	inputData.Rounding = rounding
+
+	if inputData.isReverse() {
+		inputData, _, err = reversePriceConversion(inputData, snapshot)
+		if err != nil {
+			return ConversionResponse{}, err
+		}
+	}
+
+	result, err := convert(inputData, snapshot)
+	if err != nil {
+		return ConversionResponse{}, err
+	}
+
+	quote, err := priceConversion(inputData, snapshot)
+	if err != nil {
+		return ConversionResponse{}, err
+	}
+
+	provenance, err := snapshot.provenance(inputData)
+	if err != nil {
+		return ConversionResponse{}, err
+	}
+
+	return ConversionResponse{
+		From:          inputData.Value.Currency,
+		Unit:          inputData.Unit,
+		To:            inputData.CurrencyTo,
+		UnitTo:        inputData.UnitTo,
+		Amount:        inputData.Value.String(),
+		TargetAmount:  req.TargetAmount,
+		Result:        result.String(),
+		Rounding:      rounding,
+		Profile:       quote.Profile,
+		Overlay:       quote.Overlay,
+		Route:         quote.Route,
+		MidRate:       formatRate(quote.MidRate),
+		EffectiveRate: formatRate(quote.EffectiveRate),
+		Fees:          displayMoney(quote.Fees),
+		Net:           quote.Net.String(),
+		Provenance:    provenance,
+		Date:          req.Date,
+		Timestamp:     time.Now(),
+		net:           quote.Net,
+	}, nil
+}
+
+// handleParseError reports a query that could not be parsed, with the
+// zero-based character position the problem was found at. A word that
+// could be read more than one way is reported with the currencies it may
+// stand for, and a mistyped currency with the codes it may have meant.
+func handleParseError(c echo.Context, err error) error {
+	var ambiguity *AmbiguityError
+	if errors.As(err, &ambiguity) && ambiguity.Kind != AmbiguousCurrency {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error":      ambiguity.Question(),
+			"code":       "ambiguous_" + ambiguity.Kind,
+			"position":   ambiguity.Pos,
+			"text":       ambiguity.Text,
+			"candidates": ambiguity.Candidates,
+		})
+	}
+	if ambiguity != nil {
+		candidates := make([]map[string]string, len(ambiguity.Candidates))
+		for i, code := range ambiguity.Candidates {
+			candidates[i] = map[string]string{"code": code, "name": currencyName(code)}
+		}
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error":      ambiguity.Question(),
+			"code":       "ambiguous_currency",
+			"position":   ambiguity.Pos,
+			"text":       ambiguity.Text,
+			"candidates": candidates,
+			"optional":   ambiguity.Optional,
+		})
+	}
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	body := map[string]any{"error": parseErr.Message, "code": "parse_error", "position": parseErr.Pos}
+	if len(parseErr.Suggestions) > 0 {
+		suggestions := make([]map[string]string, len(parseErr.Suggestions))
+		for i, code := range parseErr.Suggestions {
+			suggestions[i] = map[string]string{"code": code, "name": currencyName(code)}
+		}
+		body["suggestions"] = suggestions
+	}
+	return c.JSON(http.StatusBadRequest, body)
+}
+
+// handleConversionError reports a failed conversion as a client error with
+// the ConversionError's machine-readable code.
+func handleConversionError(c echo.Context, err error) error {
+	var conversionErr *ConversionError
+	if !errors.As(err, &conversionErr) {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	status := http.StatusBadRequest
+	if errors.Is(err, ErrMissingRate) || errors.Is(err, ErrOverflow) {
+		status = http.StatusUnprocessableEntity
+	}
+
+	return c.JSON(status, map[string]string{"error": err.Error(), "code": conversionErr.Code()})
+}
+
 func handleGetRates(c echo.Context) error {
 	date := c.QueryParam("date")
 
-	var rates map[string]float64
+	var snapshot RateSnapshot
 	var err error
 
 	if date != "" {
@@ -127,14 +588,97 @@ func handleGetRates(c echo.Context) error {
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
-		rates = castRateFromLatest(historicalData)
+		snapshot = historicalData
 	} else {
 		now := time.Now().Unix()
-		rates, err = caller(now)
+		snapshot, err = caller(now)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get exchange rates"})
 		}
 	}
 
-	return c.JSON(http.StatusOK, rates)
+	return c.JSON(http.StatusOK, snapshot.Rates)
+}
+
+func handleGetCurrencies(c echo.Context) error {
+	return c.JSON(http.StatusOK, listCurrencies())
+}
+
+func handleGetPairs(c echo.Context) error {
+	quotes, err := loadPairQuotes()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, quotes)
+}
+
+func handleGetBaskets(c echo.Context) error {
+	baskets, err := loadBaskets()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, baskets)
+}
+
+func handleListOverlays(c echo.Context) error {
+	overlays, err := listOverlays()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, overlays)
+}
+
+func handleGetOverlay(c echo.Context) error {
+	overlay, err := getOverlay(c.Param("name"))
+	if errors.Is(err, ErrUnknownOverlay) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error(), "code": "unknown_overlay"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, overlay)
+}
+
+// requireOverlayToken guards the routes that change overlays. They are
+// disabled unless OVERLAY_ADMIN_TOKEN is set, and then need it as a bearer
+// token.
+func requireOverlayToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := getEnvVar("OVERLAY_ADMIN_TOKEN")
+		if token == "" {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "overlay editing is disabled; set OVERLAY_ADMIN_TOKEN to enable it", "code": "overlay_editing_disabled"})
+		}
+		given, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "a valid overlay admin token is required", "code": "unauthorized"})
+		}
+		return next(c)
+	}
+}
+
+func handlePutOverlay(c echo.Context) error {
+	overlay := new(Overlay)
+	if err := c.Bind(overlay); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	overlay.Name = c.Param("name")
+
+	if err := overlay.validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error(), "code": "invalid_overlay"})
+	}
+	if err := putOverlay(*overlay); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, overlay)
+}
+
+func handleDeleteOverlay(c echo.Context) error {
+	err := deleteOverlay(c.Param("name"))
+	if errors.Is(err, ErrUnknownOverlay) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error(), "code": "unknown_overlay"})
+	} else if errors.Is(err, errShippedOverlay) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error(), "code": "shipped_overlay"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
 }
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestConvertTargetsKeepsOtherResults(t *testing.T) {
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0.8})
+	input := DataInput{Value: newMoney(100, "USD"), Targets: []string{"EUR", "JPY", "GBP"}}
+
+	responses, failures, err := convertTargets(input, &ConversionRequest{From: "USD"}, rates)
+	if len(responses) != 2 || responses[0].To != "EUR" || responses[1].To != "GBP" {
+		t.Errorf("FAILED: Expected results for EUR and GBP, got %+v", responses)
+	}
+	if len(failures) != 1 || failures[0].To != "JPY" || failures[0].Code != "missing_rate" || err == nil {
+		t.Errorf("FAILED: Expected JPY to fail with missing_rate, got %+v (%v)", failures, err)
+	}
+
+	input.Targets = []string{"JPY"}
+	responses, failures, err = convertTargets(input, &ConversionRequest{From: "USD"}, rates)
+	if len(responses) != 0 || len(failures) != 1 || err == nil {
+		t.Errorf("FAILED: Expected a lone JPY target to fail, got %+v, %+v (%v)", responses, failures, err)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+var basketCodePattern = regexp.MustCompile(`^[A-Z]{3,10}$`)
+
+// basketReferenceRates fetches the rates on a basket's reference date.
+var basketReferenceRates = getHistoricalRate
+
+// weightedQuantities holds the quantities worked out from each basket's
+// weights, keyed by everything they depend on, so the reference rates are
+// fetched once.
+var (
+	weightedMutex      sync.Mutex
+	weightedQuantities = make(map[string]map[string]*big.Rat)
+)
+
+// Basket is a composite unit priced from its components, given in one of
+// three ways. Quantities fix how much of each currency one unit holds, as
+// the SDR does. Proportions give the same quantities relative to each
+// other and are scaled to sum to one, so proportions of 50 EUR to 50 USD
+// make a unit of half a euro and half a dollar. Weights are shares of the
+// unit's value instead: on ReferenceDate a unit is worth one
+// ReferenceCurrency, split by value as the weights say, and the quantities
+// that buys are held from then on, as the ECU was. With quantities and
+// proportions the share of value each currency makes up moves with the
+// rates from the start; with weights it moves from the reference date.
+type Basket struct {
+	Code              string            `json:"code"`
+	Name              string            `json:"name,omitempty"`
+	Proportions       map[string]string `json:"proportions,omitempty"`
+	Quantities        map[string]string `json:"quantities,omitempty"`
+	Weights           map[string]string `json:"weights,omitempty"`
+	ReferenceDate     string            `json:"reference_date,omitempty"`
+	ReferenceCurrency string            `json:"reference_currency,omitempty"`
+	MinorUnits        *int              `json:"minor_units,omitempty"`
+}
+
+// loadBaskets reads BASKET_FILE, which is only read again once it changes
+// on disk, as every token of a query may be checked against it.
+func loadBaskets() ([]Basket, error) {
+	fileName := getEnvVar("BASKET_FILE")
+	if fileName == "" {
+		return nil, nil
+	}
+
+	baskets, err := loadConfigFile(fileName, func(data []byte) ([]Basket, error) {
+		var baskets []Basket
+		if err := json.Unmarshal(data, &baskets); err != nil {
+			return nil, fmt.Errorf("failed to parse basket file: %w", err)
+		}
+		for _, basket := range baskets {
+			if err := basket.validate(); err != nil {
+				return nil, err
+			}
+		}
+		return baskets, nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load basket file: %w", err)
+	}
+	return baskets, nil
+}
+
+func lookupBasket(code string) (Basket, bool) {
+	baskets, err := loadBaskets()
+	if err != nil {
+		return Basket{}, false
+	}
+	index := slices.IndexFunc(baskets, func(basket Basket) bool { return basket.Code == code })
+	if index < 0 {
+		return Basket{}, false
+	}
+	return baskets[index], true
+}
+
+func (basket Basket) validate() error {
+	if !basketCodePattern.MatchString(basket.Code) {
+		return fmt.Errorf("invalid basket code %q, please use 3 to 10 capital letters", basket.Code)
+	}
+	if _, ok := lookupCurrency(basket.Code); ok {
+		return fmt.Errorf("basket code %s is already a currency", basket.Code)
+	}
+	given := 0
+	for _, amounts := range []map[string]string{basket.Proportions, basket.Quantities, basket.Weights} {
+		if len(amounts) > 0 {
+			given++
+		}
+	}
+	if given != 1 {
+		return fmt.Errorf("basket %s needs one of proportions, quantities or weights", basket.Code)
+	}
+
+	if len(basket.Weights) > 0 {
+		if _, err := time.Parse(time.DateOnly, basket.ReferenceDate); err != nil {
+			return fmt.Errorf("basket %s needs a reference_date in YYYY-MM-DD form for its weights", basket.Code)
+		}
+		if _, ok := lookupCurrency(basket.ReferenceCurrency); !ok {
+			return fmt.Errorf("basket %s needs a reference_currency for its weights", basket.Code)
+		}
+	} else if basket.ReferenceDate != "" || basket.ReferenceCurrency != "" {
+		return fmt.Errorf("basket %s only takes a reference date and currency with weights", basket.Code)
+	}
+
+	for _, amounts := range []map[string]string{basket.Proportions, basket.Quantities, basket.Weights} {
+		for currency, amount := range amounts {
+			// Components must be plain currencies, so baskets cannot nest.
+			if _, ok := lookupCurrency(currency); !ok {
+				return fmt.Errorf("invalid basket component %s in %s", currency, basket.Code)
+			}
+			value, _, err := parseDecimal(amount)
+			if err != nil || value.Sign() <= 0 {
+				return fmt.Errorf("invalid amount %q for %s in basket %s", amount, currency, basket.Code)
+			}
+		}
+	}
+	return nil
+}
+
+// components is how much of each currency one unit of the basket holds,
+// in a stable order. The map may be shared and must not be modified.
+func (basket Basket) components() ([]string, map[string]*big.Rat, error) {
+	var held map[string]*big.Rat
+	if len(basket.Weights) > 0 {
+		var err error
+		if held, err = basket.weightedQuantities(); err != nil {
+			return nil, nil, err
+		}
+	} else if len(basket.Quantities) > 0 {
+		held = basketShares(basket.Quantities, false)
+	} else {
+		held = basketShares(basket.Proportions, true)
+	}
+
+	currencies := make([]string, 0, len(held))
+	for currency := range held {
+		currencies = append(currencies, currency)
+	}
+	slices.Sort(currencies)
+	return currencies, held, nil
+}
+
+// basketShares reads amounts, scaled to sum to one when normalize is set.
+func basketShares(amounts map[string]string, normalize bool) map[string]*big.Rat {
+	held := make(map[string]*big.Rat)
+	total := new(big.Rat)
+	for currency, amount := range amounts {
+		value, _, _ := parseDecimal(amount)
+		held[currency] = value
+		total.Add(total, value)
+	}
+	if normalize {
+		for _, value := range held {
+			value.Quo(value, total)
+		}
+	}
+	return held
+}
+
+// weightedQuantities works out the quantities a unit of a weighted basket
+// holds: each currency's share of one ReferenceCurrency, bought at the
+// rates on ReferenceDate.
+func (basket Basket) weightedQuantities() (map[string]*big.Rat, error) {
+	key := fmt.Sprint(basket.Code, basket.ReferenceDate, basket.ReferenceCurrency, basket.Weights)
+
+	weightedMutex.Lock()
+	defer weightedMutex.Unlock()
+
+	if held, ok := weightedQuantities[key]; ok {
+		return held, nil
+	}
+
+	snapshot, err := basketReferenceRates(basket.ReferenceDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the reference rates of basket %s: %w", basket.Code, err)
+	}
+	held := basketShares(basket.Weights, true)
+	for currency, share := range held {
+		if currency == basket.ReferenceCurrency {
+			continue
+		}
+		leg := DataInput{Value: Money{Currency: currency}, CurrencyTo: basket.ReferenceCurrency, Date: basket.ReferenceDate}
+		rate, _, err := pairRate(leg, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		share.Quo(share, rate)
+	}
+
+	weightedQuantities[key] = held
+	return held, nil
+}
+
+// value prices one unit of the basket in currency by pricing each component
+// on its own, so historical dates and legacy currencies carry through.
+func (basket Basket) value(currency string, dataInput DataInput, snapshot RateSnapshot) (*big.Rat, error) {
+	currencies, held, err := basket.components()
+	if err != nil {
+		return nil, err
+	}
+	total := new(big.Rat)
+
+	for _, component := range currencies {
+		leg := dataInput
+		leg.Value.Currency, leg.CurrencyTo = component, currency
+		rate, _, err := pairRate(leg, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		total.Add(total, rate.Mul(rate, held[component]))
+	}
+	return total, nil
+}
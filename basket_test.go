@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func useTestBaskets(t *testing.T, baskets []Basket) {
+	fileName := filepath.Join(t.TempDir(), "baskets.json")
+	data, _ := json.Marshal(baskets)
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BASKET_FILE", fileName)
+}
+
+func TestConvertBaskets(t *testing.T) {
+	places := 4
+	useTestBaskets(t, []Basket{
+		{Code: "MIX", Proportions: map[string]string{"EUR": "50", "USD": "50"}, MinorUnits: &places},
+		{Code: "QTY", Quantities: map[string]string{"USD": "1", "EUR": "2"}, MinorUnits: &places},
+		{Code: "MARK", Quantities: map[string]string{"DEM": "1.95583"}},
+	})
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.8, "GBP": 0.5})
+
+	cases := []struct {
+		Input    DataInput
+		Expected string
+	}{
+		{DataInput{Value: newMoney(1, "MIX"), CurrencyTo: "USD"}, "1.13"},
+		{DataInput{Value: newMoney(100, "USD"), CurrencyTo: "MIX"}, "88.8889"},
+		{DataInput{Value: newMoney(1, "QTY"), CurrencyTo: "GBP"}, "1.75"},
+		{DataInput{Value: newMoney(7, "QTY"), CurrencyTo: "MIX"}, "21.7778"},
+		{DataInput{Value: newMoney(1, "MARK"), CurrencyTo: "USD", Date: "2001-05-01"}, "1.25"},
+	}
+
+	for _, testCase := range cases {
+		result, err := convert(testCase.Input, rates)
+		if err != nil || result.String() != testCase.Expected {
+			t.Errorf("FAILED: convert(%v) Expected %s, got %s (%v)", testCase.Input, testCase.Expected, result, err)
+		}
+	}
+
+	if dataInput := parseTestQuery(t, "100 USD to MIX"); dataInput.CurrencyTo != "MIX" {
+		t.Errorf("FAILED: parseQuery Expected the MIX basket as target, got %+v", dataInput)
+	}
+}
+
+func TestConvertWeightedBasket(t *testing.T) {
+	places := 4
+	useTestBaskets(t, []Basket{
+		{Code: "HALVES", Weights: map[string]string{"EUR": "50", "USD": "50"}, ReferenceDate: "2024-01-02", ReferenceCurrency: "USD", MinorUnits: &places},
+	})
+
+	// On the reference date a euro is worth 1.25 USD, so a unit holds half
+	// a dollar and 0.4 EUR, which is also worth half a dollar.
+	fetches := 0
+	basketReferenceRates = func(date string) (RateSnapshot, error) {
+		fetches++
+		if date != "2024-01-02" {
+			t.Errorf("FAILED: Expected the reference rates of 2024-01-02, got %s", date)
+		}
+		return newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.8}), nil
+	}
+	t.Cleanup(func() { basketReferenceRates = getHistoricalRate })
+
+	cases := []struct {
+		Rates    RateSnapshot
+		Expected string
+	}{
+		{newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.8}), "1.00"},
+		{newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.5}), "1.30"},
+		{newRateSnapshot("EUR", map[string]float64{"EUR": 1, "USD": 1.6}), "1.14"},
+	}
+
+	for _, testCase := range cases {
+		result, err := convert(DataInput{Value: newMoney(1, "HALVES"), CurrencyTo: "USD"}, testCase.Rates)
+		if err != nil || result.String() != testCase.Expected {
+			t.Errorf("FAILED: convert of 1 HALVES at %v Expected %s USD, got %s (%v)", testCase.Rates.Rates, testCase.Expected, result, err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("FAILED: Expected the reference rates to be fetched once, got %d", fetches)
+	}
+}
+
+func TestBasketValidation(t *testing.T) {
+	invalid := []Basket{
+		{Code: "EUR", Proportions: map[string]string{"USD": "1"}},
+		{Code: "mix", Proportions: map[string]string{"USD": "1"}},
+		{Code: "MIX"},
+		{Code: "MIX", Proportions: map[string]string{"USD": "1"}, Quantities: map[string]string{"EUR": "1"}},
+		{Code: "MIX", Proportions: map[string]string{"XXX": "1"}},
+		{Code: "MIX", Quantities: map[string]string{"USD": "0"}},
+		{Code: "MIX", Weights: map[string]string{"USD": "1"}, ReferenceCurrency: "USD"},
+		{Code: "MIX", Weights: map[string]string{"USD": "1"}, ReferenceDate: "2024-01-02"},
+		{Code: "MIX", Quantities: map[string]string{"USD": "1"}, ReferenceDate: "2024-01-02"},
+		{Code: "MIX", Weights: map[string]string{"USD": "1"}, Proportions: map[string]string{"EUR": "1"}, ReferenceDate: "2024-01-02", ReferenceCurrency: "USD"},
+	}
+
+	for _, basket := range invalid {
+		if err := basket.validate(); err == nil {
+			t.Errorf("FAILED: Expected %+v to be rejected", basket)
+		}
+	}
+}
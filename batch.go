@@ -0,0 +1,91 @@
+package main
+
+import "math/big"
+
+// BatchTotal is the sum of the final results of a batch in one currency.
+type BatchTotal struct {
+	Value Money  `json:"value"`
+	Unit  string `json:"unit,omitempty"`
+}
+
+// listInputs is input once for each of sources, or just input when the
+// query named a single amount.
+func listInputs(input DataInput, sources []QuerySource) []DataInput {
+	if len(sources) == 0 {
+		return []DataInput{input}
+	}
+	inputs := make([]DataInput, len(sources))
+	for i, source := range sources {
+		input.Value = Money{Amount: source.Amount, Scale: source.Scale, Currency: source.Currency}
+		input.Unit = source.Unit
+		inputs[i] = input
+	}
+	return inputs
+}
+
+// runBatch performs every conversion a query asks for: each input to each
+// of its targets, and each result on along chain, converting the rounded
+// result of one step in the next. convert performs a single conversion and
+// returns the amount received, after any fees. A conversion that fails is
+// passed to failed and ends its target's chain, and the batch carries on
+// with the next target. The totals add up the final results by currency,
+// in the order the currencies were first met, and are left out when any
+// conversion failed, as they would not add up to what was asked.
+func runBatch(inputs []DataInput, chain []string, convert func(DataInput) (Money, error), failed func(DataInput, error)) []BatchTotal {
+	var totals []BatchTotal
+	complete := true
+	for _, input := range inputs {
+		for _, target := range input.Targets {
+			if result, unit, err := runChain(input.withTarget(target), chain, convert); err != nil {
+				failed(input.withTarget(target), err)
+				complete = false
+			} else {
+				totals = addTotal(totals, result, unit)
+			}
+		}
+	}
+	if !complete {
+		return nil
+	}
+	return totals
+}
+
+// runChain converts step and then its result along chain, returning the
+// final result and its unit.
+func runChain(step DataInput, chain []string, convert func(DataInput) (Money, error)) (Money, string, error) {
+	result, err := convert(step)
+	if err != nil {
+		return Money{}, "", err
+	}
+
+	for _, currency := range chain {
+		step = DataInput{
+			Value:      result,
+			Unit:       step.UnitTo,
+			CurrencyTo: currency,
+			Targets:    []string{currency},
+			Date:       step.Date,
+			Rounding:   Rounding{Mode: step.Rounding.Mode},
+			Profile:    step.Profile,
+			Overlay:    step.Overlay,
+		}
+		if result, err = convert(step); err != nil {
+			return Money{}, "", err
+		}
+	}
+	return result, step.UnitTo, nil
+}
+
+func addTotal(totals []BatchTotal, value Money, unit string) []BatchTotal {
+	for i, total := range totals {
+		if total.Value.Currency == value.Currency && total.Unit == unit {
+			totals[i].Value = Money{
+				Amount:   new(big.Rat).Add(total.Value.amount(), value.amount()),
+				Scale:    max(total.Value.Scale, value.Scale),
+				Currency: value.Currency,
+			}
+			return totals
+		}
+	}
+	return append(totals, BatchTotal{Value: Money{Amount: new(big.Rat).Set(value.amount()), Scale: value.Scale, Currency: value.Currency}, Unit: unit})
+}
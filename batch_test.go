@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+)
+
+func TestParseQueryLists(t *testing.T) {
+	cases := []struct {
+		Input   string
+		Sources []string
+		Targets []string
+		Chain   []string
+		Total   bool
+	}{
+		{"10 EUR, 20 GBP and 3000 JPY to USD", []string{"10 EUR", "20 GBP", "3000 JPY"}, []string{"USD"}, nil, false},
+		{"10 EUR and 20 GBP in USD and CHF in total", []string{"10 EUR", "20 GBP"}, []string{"USD", "CHF"}, nil, true},
+		{"What is five hundred euros, 2k quid and 1,000 yen altogether in dollars?", []string{"500 EUR", "2000 GBP", "1000 JPY"}, []string{"USD"}, nil, true},
+		{"100 USD to EUR then to CHF", nil, []string{"EUR"}, []string{"CHF"}, false},
+		{"100 USD to EUR, then CHF, then GBP", nil, []string{"EUR"}, []string{"CHF", "GBP"}, false},
+		{"10 EUR and 20 GBP to USD then JPY", []string{"10 EUR", "20 GBP"}, []string{"USD"}, []string{"JPY"}, false},
+	}
+
+	for _, testCase := range cases {
+		t.Setenv("LOCALE", "en-US")
+		query, err := parseQuery(testCase.Input)
+		if err != nil {
+			t.Errorf("FAILED: parseQuery(%q) returned %v", testCase.Input, err)
+			continue
+		}
+		var sources []string
+		for _, source := range query.Sources {
+			sources = append(sources, Money{Amount: source.Amount, Scale: source.Scale}.String()+" "+source.Currency)
+		}
+		if !slices.Equal(sources, testCase.Sources) || !slices.Equal(query.Targets, testCase.Targets) || !slices.Equal(query.Chain, testCase.Chain) || query.Total != testCase.Total {
+			t.Errorf("FAILED: %q read as %v to %v then %v (total %v)", testCase.Input, sources, query.Targets, query.Chain, query.Total)
+		}
+	}
+}
+
+func TestParseQueryListErrors(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Position int
+		Message  string
+	}{
+		{"10 EUR and 20 to USD", 14, `expected a currency, found "to"`},
+		{"100 USD to EUR and GBP then CHF", 23, "only a single target can be converted on"},
+		{"100 USD to EUR then CHF GBP", 24, `expected "then" before another currency in a chain`},
+		{"100 USD to EUR then", 19, "expected a target currency"},
+		{"1 USD to EUR and", 16, "expected a target currency"},
+		{"1 USD to EUR, GBP,", 18, "expected a target currency"},
+		{"10 EUR and 20 GBP to USD over the last week", 0, "a range of dates takes a single amount"},
+	}
+
+	for _, testCase := range cases {
+		_, err := parseQuery(testCase.Input)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Pos != testCase.Position || parseErr.Message != testCase.Message {
+			t.Errorf("FAILED: parseQuery(%q) Expected %q at %d, got %v", testCase.Input, testCase.Message, testCase.Position, err)
+		}
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	// Every conversion doubles the amount, so a chain of two quadruples it.
+	var steps []string
+	double := func(step DataInput) (Money, error) {
+		steps = append(steps, step.Value.Currency+"→"+step.CurrencyTo)
+		return Money{Amount: new(big.Rat).Mul(step.Value.amount(), big.NewRat(2, 1)), Scale: 2, Currency: step.CurrencyTo}, nil
+	}
+
+	query, err := parseQuery("10 EUR and 20 GBP to USD then CHF")
+	if err != nil {
+		t.Fatalf("FAILED: parseQuery returned %v", err)
+	}
+	unexpected := func(step DataInput, err error) {
+		t.Errorf("FAILED: runBatch reported %v converting to %s", err, step.CurrencyTo)
+	}
+	totals := runBatch(listInputs(query.dataInput(), query.Sources), query.Chain, double, unexpected)
+
+	expectedSteps := []string{"EUR→USD", "USD→CHF", "GBP→USD", "USD→CHF"}
+	if !slices.Equal(steps, expectedSteps) {
+		t.Errorf("FAILED: runBatch converted %v, expected %v", steps, expectedSteps)
+	}
+	if len(totals) != 1 || totals[0].Value.Currency != "CHF" || totals[0].Value.String() != "120.00" {
+		t.Errorf("FAILED: runBatch totals %+v, expected 120.00 CHF", totals)
+	}
+
+	// A failed target is reported and the rest of the batch goes ahead.
+	steps = nil
+	var failed []string
+	failing := func(step DataInput) (Money, error) {
+		if step.Value.Currency == "GBP" {
+			return Money{}, &ConversionError{Kind: ErrMissingRate, Currency: step.CurrencyTo}
+		}
+		return double(step)
+	}
+	totals = runBatch(listInputs(query.dataInput(), query.Sources), query.Chain, failing, func(step DataInput, err error) {
+		failed = append(failed, step.Value.Currency)
+	})
+	if !slices.Equal(steps, []string{"EUR→USD", "USD→CHF"}) || !slices.Equal(failed, []string{"GBP"}) || totals != nil {
+		t.Errorf("FAILED: runBatch Expected EUR to go through, GBP to fail and no totals, got %v, %v and %+v", steps, failed, totals)
+	}
+}
+
+func TestRunBatchAfterFees(t *testing.T) {
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0.8})
+	query, err := parseQuery("100 USD to EUR then GBP")
+	if err != nil {
+		t.Fatalf("FAILED: parseQuery returned %v", err)
+	}
+	input := query.dataInput()
+	input.Profile = "bank"
+
+	// 100 USD is 85.99 EUR after the bank's fees, and the chain goes on
+	// from those 85.99 EUR rather than from the 90.00 before fees.
+	totals := runBatch(listInputs(input, query.Sources), query.Chain, func(step DataInput) (Money, error) {
+		return displayConversion(step, rates, "")
+	}, func(step DataInput, err error) {
+		t.Errorf("FAILED: runBatch reported %v converting to %s", err, step.CurrencyTo)
+	})
+
+	step := DataInput{Value: Money{Amount: big.NewRat(8599, 100), Scale: 2, Currency: "EUR"}, CurrencyTo: "GBP", Profile: "bank"}
+	expected, err := priceConversion(step, rates)
+	if err != nil {
+		t.Fatalf("FAILED: priceConversion returned %v", err)
+	}
+	if len(totals) != 1 || totals[0].Value.String() != expected.Net.String() {
+		t.Errorf("FAILED: Expected the chain to end at %s GBP, got %+v", expected.Net, totals)
+	}
+}
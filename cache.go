@@ -1,31 +1,34 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 )
 
-func useCache(now int64, force bool) (Latest, error) {
-	var latestData Latest
+func useCache(now int64, force bool) (RateSnapshot, error) {
+	var snapshot RateSnapshot
 	fileName := getEnvVar("FILE_NAME")
 
 	cacheExists := checkIfCacheExist(fileName)
 
 	if cacheExists {
-		cacheData := readFromCache()
-		secondsElapsed := now - cacheData.Timestamp
+		envelope, err := readEnvelopeFromCache(fileName)
+		if err != nil {
+			return snapshot, err
+		}
+		secondsElapsed := now - envelope.Timestamp
 		cacheExpiry := getIntEnvVar("CACHE_EXPIRY_IN_SECONDS")
 		if force || secondsElapsed <= cacheExpiry {
-			latestData = cacheData
-			return latestData, nil
+			snapshot = snapshotFromEnvelope(envelope, CacheLayerFile)
+			snapshot.Stale = secondsElapsed > cacheExpiry
+			return snapshot, nil
 		} else {
-			return latestData, errors.New("expired cache")
+			return snapshot, errors.New("expired cache")
 		}
 	} else {
-		return latestData, errors.New("error: Cache does not exist")
+		return snapshot, errors.New("error: Cache does not exist")
 	}
 }
 
@@ -42,17 +45,26 @@ func checkIfCacheExist(fileName string) bool {
 }
 
 func readFromCache() Latest {
-	var latest Latest
-	fileName := getEnvVar("FILE_NAME")
-	jsonFile, err := os.Open(fileName)
+	envelope, err := readEnvelopeFromCache(getEnvVar("FILE_NAME"))
 	if err != nil {
 		fmt.Println(err)
 	}
+	return envelope.latest()
+}
+
+func readEnvelopeFromCache(fileName string) (CacheEnvelope, error) {
+	jsonFile, err := os.Open(fileName)
+	if err != nil {
+		return CacheEnvelope{}, err
+	}
 	defer jsonFile.Close()
-	responseData, _ := io.ReadAll(jsonFile)
 
-	json.Unmarshal([]byte(responseData), &latest)
-	return latest
+	responseData, err := io.ReadAll(jsonFile)
+	if err != nil {
+		return CacheEnvelope{}, err
+	}
+
+	return decodeCacheEnvelope(responseData)
 }
 
 func writeToCache(data []byte, fileName string) {
@@ -3,42 +3,79 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 type Latest struct {
-	Timestamp int64          `json:"timestamp"`
-	Rates     map[string]any `json:"rates"`
+	Disclaimer string         `json:"disclaimer,omitempty"`
+	License    string         `json:"license,omitempty"`
+	Timestamp  int64          `json:"timestamp"`
+	Base       string         `json:"base,omitempty"`
+	Rates      map[string]any `json:"rates"`
 }
 
 func checkForForce() bool {
-	if len(os.Args) > 1 && (os.Args[1] == "--force" || os.Args[1] == "-f") {
-		return true
+	for _, arg := range os.Args[1:] {
+		if arg == "--force" || arg == "-f" {
+			return true
+		}
 	}
 	return false
 }
 
-func caller(now int64) (map[string]float64, error) {
-	rates := make(map[string]float64)
+func checkForVerbose() bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--verbose" || arg == "-v" {
+			return true
+		}
+	}
+	return false
+}
+
+// getFlagValue returns the value of a "--name=value" or "--name value"
+// command line flag, or "" when it is absent.
+func getFlagValue(name string) string {
+	flag := "--" + name
+	for i, arg := range os.Args[1:] {
+		if value, found := strings.CutPrefix(arg, flag+"="); found {
+			return value
+		}
+		if arg == flag && i+2 < len(os.Args) {
+			return os.Args[i+2]
+		}
+	}
+	return ""
+}
+
+// caller returns the latest rates together with the quoted pairs that are
+// routed through for assets the rate table lacks.
+func caller(now int64) (RateSnapshot, error) {
+	snapshot, err := latestRates(now)
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Quotes, err = loadPairQuotes()
+	return snapshot, err
+}
 
+func latestRates(now int64) (RateSnapshot, error) {
 	cache, cacheErr := useCache(now, checkForForce())
 	if cacheErr == nil {
-		rates = castRateFromLatest(cache)
-		return rates, nil
+		return cache, nil
 	}
 
 	api, apiErr := useApi("")
 	if apiErr == nil {
-		rates = castRateFromLatest(api)
-		return rates, nil
+		return api, nil
 	}
 
 	forcedCache, forcedCacheErr := useCache(now, true)
 	if forcedCacheErr == nil {
-		rates = castRateFromLatest(forcedCache)
-		return rates, nil
+		return forcedCache, nil
 	}
 
-	return rates, forcedCacheErr
+	return forcedCache, forcedCacheErr
 }
 
 func castRateFromLatest(latestData Latest) map[string]float64 {
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// cachedConfig is a decoded configuration file, with the modification time
+// and size of the file it was decoded from.
+type cachedConfig struct {
+	modTime time.Time
+	size    int64
+	value   any
+}
+
+// configCache holds the configuration files named by PRICING_FILE,
+// PAIR_QUOTES_FILE, BASKET_FILE, OVERLAY_FILE and OVERLAY_STORE, which are
+// consulted for every token of a query and every conversion.
+var (
+	configMutex sync.Mutex
+	configCache = make(map[string]cachedConfig)
+)
+
+// loadConfigFile decodes fileName with decode, reusing the last result
+// until the file changes on disk. Callers share the value it returns and
+// must not modify it. Errors, including the file not existing, are those
+// of os.Stat and os.ReadFile, and are not cached.
+func loadConfigFile[T any](fileName string, decode func(data []byte) (T, error)) (T, error) {
+	var zero T
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return zero, err
+	}
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	if cached, ok := configCache[fileName]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		if value, ok := cached.value.(T); ok {
+			return value, nil
+		}
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return zero, err
+	}
+	value, err := decode(data)
+	if err != nil {
+		return zero, err
+	}
+	configCache[fileName] = cachedConfig{modTime: info.ModTime(), size: info.Size(), value: value}
+	return value, nil
+}
+
+// forgetConfigFile drops the cached copy of fileName, for callers that have
+// just written to it.
+func forgetConfigFile(fileName string) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	delete(configCache, fileName)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "config.json")
+	decodes := 0
+	decode := func(data []byte) (string, error) {
+		decodes++
+		return string(data), nil
+	}
+
+	if err := os.WriteFile(fileName, []byte("first"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if value, err := loadConfigFile(fileName, decode); err != nil || value != "first" {
+			t.Errorf("FAILED: loadConfigFile Expected first, got %q (%v)", value, err)
+		}
+	}
+	if decodes != 1 {
+		t.Errorf("FAILED: Expected an unchanged file to be decoded once, got %d", decodes)
+	}
+
+	if err := os.WriteFile(fileName, []byte("second"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := loadConfigFile(fileName, decode); err != nil || value != "second" {
+		t.Errorf("FAILED: loadConfigFile Expected the changed file to be read again, got %q (%v)", value, err)
+	}
+
+	if _, err := loadConfigFile(filepath.Join(t.TempDir(), "missing.json"), decode); !os.IsNotExist(err) {
+		t.Errorf("FAILED: Expected a missing file to report os.ErrNotExist, got %v", err)
+	}
+}
@@ -1,33 +1,215 @@
 package main
 
-import "math"
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
 
-func convert(dataInput DataInput, rates map[string]float64) float64 {
+// maxAmountDigits bounds amounts and results to keep them well inside what
+// JSON consumers decoding into float64 can represent.
+const maxAmountDigits = 30
 
-	var conversion float64
-	precision := int(getIntEnvVar("PRECISION"))
+var maxAmount = new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(maxAmountDigits), nil))
 
-	var convertFromUsd = func(value float64, targetRate float64) float64 {
-		var convertedValue float64 = value * targetRate
-		return convertedValue
+func convert(dataInput DataInput, snapshot RateSnapshot) (Money, error) {
+	if err := validateConversion(dataInput); err != nil {
+		return Money{Currency: dataInput.CurrencyTo}, err
 	}
 
-	if dataInput.CurrencyFrom == "USD" {
-		conversion = convertFromUsd(dataInput.Value, rates[dataInput.CurrencyTo])
-	} else {
-		rateFrom := rates[dataInput.CurrencyFrom]
-		var value float64 = dataInput.Value / rateFrom
-		conversion = convertFromUsd(value, rates[dataInput.CurrencyTo])
+	rate, _, err := conversionRate(dataInput, snapshot)
+	if err != nil {
+		return Money{Currency: dataInput.CurrencyTo}, err
+	}
+	return convertAtRate(dataInput.Value, dataInput.CurrencyTo, rate, dataInput.Rounding)
+}
+
+// convertAtRate multiplies value by rate and rounds into currency, using the
+// currency's default rounding when none was requested.
+func convertAtRate(value Money, currency string, rate *big.Rat, rounding Rounding) (Money, error) {
+	if rounding.Mode == "" {
+		rounding = defaultRounding(currency)
+	}
+	result := Money{Amount: new(big.Rat), Scale: rounding.places(), Currency: currency}
+
+	conversion := new(big.Rat).Mul(value.amount(), rate)
+	if new(big.Rat).Abs(conversion).Cmp(maxAmount) >= 0 {
+		return result, &ConversionError{Kind: ErrOverflow, Currency: currency}
+	}
+
+	result.Amount = conversion
+	return result.RoundWith(rounding), nil
+}
+
+// RateSource records where a conversion rate came from: the overlay that
+// supplied it, if any, and the currencies it was priced through, such as
+// EUR, USD, GBP for a cross through a USD-based table. Route explains the
+// path when it was found in the rate graph, as in "ADA→USDT→EUR".
+type RateSource struct {
+	Overlay string
+	Path    []string
+	Route   string
+}
+
+// pivotCurrency is the currency crosses are triangulated through before
+// falling back to the snapshot's base. PIVOT_CURRENCY picks it; by default
+// it is the base itself.
+func pivotCurrency(snapshot RateSnapshot) string {
+	if pivot := strings.ToUpper(getEnvVar("PIVOT_CURRENCY")); pivot != "" {
+		return pivot
+	}
+	return snapshot.base()
+}
+
+// exchangeRate is the mid-market price of one unit of from in to, and the
+// path of currencies it was priced through. A direct quote for the pair is
+// used when the provider published one; otherwise the pair is crossed
+// through the pivot, and then through the base of the rate table.
+func exchangeRate(from string, to string, snapshot RateSnapshot) (*big.Rat, []string, error) {
+	if rate, ok := snapshot.quote(from, to); ok {
+		return rate, []string{from, to}, nil
+	}
+
+	// missing names the currency that has no rate against the pivot, so the
+	// error points at the side of the pair that is actually absent.
+	missing := from
+	for _, pivot := range []string{pivotCurrency(snapshot), snapshot.base()} {
+		switch pivot {
+		case from:
+			// The direct quote already failed, so to has no rate here.
+			missing = to
+			continue
+		case to:
+			missing = from
+			continue
+		}
+		first, firstOK := snapshot.quote(from, pivot)
+		second, secondOK := snapshot.quote(pivot, to)
+		if !firstOK {
+			missing = from
+			continue
+		}
+		if !secondOK {
+			missing = to
+			continue
+		}
+		return first.Mul(first, second), []string{from, pivot, to}, nil
+	}
+
+	return nil, nil, &ConversionError{Kind: ErrMissingRate, Currency: missing}
+}
+
+// conversionRate is the rate dataInput converts at: the requested overlay's
+// rate when it covers the pair on dataInput.Date, otherwise the market rate.
+// Where the rate came from is returned alongside. Metal quantities in
+// grams, kilograms or tolas are scaled from the per troy ounce quotes.
+func conversionRate(dataInput DataInput, snapshot RateSnapshot) (*big.Rat, RateSource, error) {
+	rate, source, err := pairRate(dataInput, snapshot)
+	if err != nil {
+		return nil, source, err
+	}
+
+	rate.Mul(rate, troyOunces(dataInput.Unit))
+	return rate.Quo(rate, troyOunces(dataInput.UnitTo)), source, nil
+}
+
+func pairRate(dataInput DataInput, snapshot RateSnapshot) (*big.Rat, RateSource, error) {
+	from, to := dataInput.Value.Currency, dataInput.CurrencyTo
+
+	if dataInput.Overlay != "" {
+		overlay, err := getOverlay(dataInput.Overlay)
+		if err != nil {
+			return nil, RateSource{}, err
+		}
+		if rate, ok := overlay.rate(from, to, dataInput.Date); ok {
+			// overlay.rate hands out a fresh value that is safe to scale.
+			return rate, RateSource{Overlay: overlay.Name, Path: []string{from, to}}, nil
+		}
+	}
+
+	// Baskets are priced component by component, and a conversion into one
+	// is the inverse of pricing the basket in the source currency.
+	if basket, ok := lookupBasket(from); ok {
+		rate, err := basket.value(to, dataInput, snapshot)
+		if err != nil {
+			return nil, RateSource{}, err
+		}
+		return rate, RateSource{Path: []string{from, to}}, nil
+	}
+	if basket, ok := lookupBasket(to); ok {
+		rate, err := basket.value(from, dataInput, snapshot)
+		if err != nil {
+			return nil, RateSource{}, err
+		}
+		return rate.Inv(rate), RateSource{Path: []string{from, to}}, nil
+	}
+
+	// Legacy currencies are priced through whatever replaced them, or
+	// whatever they replaced, using the fixed conversion factor between
+	// the two, so DEM to FRF in 2001 is a pure euro cross.
+	routedFrom, fromFactor, err := legacyRoute(from, dataInput.Date)
+	if err != nil {
+		return nil, RateSource{}, err
+	}
+	routedTo, toFactor, err := legacyRoute(to, dataInput.Date)
+	if err != nil {
+		return nil, RateSource{}, err
 	}
 
-	return math.Round(conversion*(math.Pow10(precision))) / math.Pow10(precision)
+	rate, path, route := big.NewRat(1, 1), []string{routedFrom}, ""
+	if routedFrom != routedTo {
+		rate, path, err = exchangeRate(routedFrom, routedTo, snapshot)
+		// Pairs the rate table cannot price may still be reachable
+		// through quoted pairs.
+		if errors.Is(err, ErrMissingRate) {
+			if best, ok := snapshot.rateGraph().route(routedFrom, routedTo, routingMode()); ok {
+				rate, path, route, err = best.Rate, best.Assets, best.String(), nil
+			}
+		}
+		if err != nil {
+			return nil, RateSource{}, err
+		}
+	}
+	if routedFrom != from {
+		path = append([]string{from}, path...)
+	}
+	if routedTo != to {
+		path = append(path, to)
+	}
+
+	rate.Mul(rate, toFactor)
+	return rate.Quo(rate, fromFactor), RateSource{Path: path, Route: route}, nil
 }
 
-func checkValidCurrency(rates map[string]float64, currency string) bool {
-	for key := range rates {
-		if currency == key {
-			return true
+func validateConversion(dataInput DataInput) error {
+	if dataInput.Value.Sign() <= 0 {
+		return &ConversionError{Kind: ErrNonPositiveAmount, Detail: dataInput.Value.String()}
+	}
+	if dataInput.Value.amount().Cmp(maxAmount) >= 0 {
+		return &ConversionError{Kind: ErrOverflow, Currency: dataInput.Value.Currency}
+	}
+
+	for _, currency := range []string{dataInput.Value.Currency, dataInput.CurrencyTo} {
+		if !checkValidCurrency(currency) {
+			return &ConversionError{Kind: ErrUnknownCurrency, Currency: currency}
 		}
 	}
-	return false
+
+	if dataInput.Unit != "" && !isPreciousMetal(dataInput.Value.Currency) {
+		return &ConversionError{Kind: ErrInvalidUnit, Currency: dataInput.Value.Currency, Detail: dataInput.Unit}
+	}
+	if dataInput.UnitTo != "" && !isPreciousMetal(dataInput.CurrencyTo) {
+		return &ConversionError{Kind: ErrInvalidUnit, Currency: dataInput.CurrencyTo, Detail: dataInput.UnitTo}
+	}
+
+	return nil
+}
+
+func checkValidCurrency(currency string) bool {
+	_, ok := lookupCurrency(currency)
+	if ok || isQuotedAsset(currency) {
+		return true
+	}
+	_, ok = lookupBasket(currency)
+	return ok
 }
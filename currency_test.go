@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestConvertErrors(t *testing.T) {
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0})
+
+	cases := []struct {
+		Input    DataInput
+		Expected error
+	}{
+		{DataInput{Value: newMoney(1, "XXX"), CurrencyTo: "EUR"}, ErrUnknownCurrency},
+		{DataInput{Value: newMoney(1, "USD"), CurrencyTo: "QQQ"}, ErrUnknownCurrency},
+		{DataInput{Value: newMoney(1, "USD"), CurrencyTo: "JPY"}, ErrMissingRate},
+		{DataInput{Value: newMoney(1, "GBP"), CurrencyTo: "EUR"}, ErrMissingRate},
+		{DataInput{Value: newMoney(0, "USD"), CurrencyTo: "EUR"}, ErrNonPositiveAmount},
+		{DataInput{Value: newMoney(-5, "USD"), CurrencyTo: "EUR"}, ErrNonPositiveAmount},
+		{DataInput{Value: Money{Amount: new(big.Rat).Mul(maxAmount, big.NewRat(2, 1)), Currency: "USD"}, CurrencyTo: "EUR"}, ErrOverflow},
+	}
+
+	for _, testCase := range cases {
+		_, err := convert(testCase.Input, rates)
+		if !errors.Is(err, testCase.Expected) {
+			t.Errorf("FAILED: convert(%v) Expected %v, got %v", testCase.Input, testCase.Expected, err)
+		}
+	}
+}
+
+func TestConversionErrorCode(t *testing.T) {
+	_, err := convert(DataInput{Value: newMoney(1, "XXX"), CurrencyTo: "EUR"}, newRateSnapshot("USD", map[string]float64{"EUR": 0.9}))
+
+	var conversionErr *ConversionError
+	if !errors.As(err, &conversionErr) || conversionErr.Code() != "unknown_currency" || conversionErr.Currency != "XXX" {
+		t.Errorf("FAILED: Expected unknown_currency for XXX, got %v", err)
+	}
+}
+
+func TestMissingRateNamesAbsentCurrency(t *testing.T) {
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9})
+
+	cases := []struct {
+		Input    DataInput
+		Expected string
+	}{
+		{DataInput{Value: newMoney(1, "USD"), CurrencyTo: "JPY"}, "JPY"},
+		{DataInput{Value: newMoney(1, "EUR"), CurrencyTo: "JPY"}, "JPY"},
+		{DataInput{Value: newMoney(1, "JPY"), CurrencyTo: "USD"}, "JPY"},
+		{DataInput{Value: newMoney(1, "JPY"), CurrencyTo: "EUR"}, "JPY"},
+	}
+
+	for _, testCase := range cases {
+		_, err := convert(testCase.Input, rates)
+
+		var conversionErr *ConversionError
+		if !errors.As(err, &conversionErr) || conversionErr.Code() != "missing_rate" || conversionErr.Currency != testCase.Expected {
+			t.Errorf("FAILED: convert(%v) Expected missing_rate for %s, got %v", testCase.Input, testCase.Expected, err)
+		}
+	}
+}
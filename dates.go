@@ -0,0 +1,535 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// earliestRateDate is the first day the provider has historical rates for.
+const earliestRateDate = "1999-01-01"
+
+// DateOrder is the order day and month are written in, as in "05/01/2022".
+type DateOrder string
+
+const (
+	DayFirst   DateOrder = "DMY"
+	MonthFirst DateOrder = "MDY"
+)
+
+// monthFirstRegions write the month before the day.
+var monthFirstRegions = []string{"US", "PH", "FM", "PR"}
+
+var monthNames = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+}
+
+// periodWords are the spans "ago", "end of" and "start of" count in.
+var periodWords = map[string]string{
+	"day": "day", "days": "day",
+	"week": "week", "weeks": "week",
+	"month": "month", "months": "month",
+	"quarter": "quarter", "quarters": "quarter",
+	"year": "year", "years": "year",
+}
+
+var ordinalSuffixes = []string{"st", "nd", "rd", "th"}
+
+// dateOrder is the order set by DATE_ORDER, "DMY" or "MDY", if any.
+func dateOrder() DateOrder {
+	switch order := DateOrder(strings.ToUpper(getEnvVar("DATE_ORDER"))); order {
+	case DayFirst, MonthFirst:
+		return order
+	}
+	return ""
+}
+
+// dateOrder is the order the locale writes day and month in, or empty
+// when the locale is not known.
+func (locale Locale) dateOrder() DateOrder {
+	switch {
+	case slices.Contains(monthFirstRegions, locale.Region):
+		return MonthFirst
+	case locale.decimalMark() != 0:
+		return DayFirst
+	}
+	return ""
+}
+
+// DateMatch is a run of tokens naming a day. Latest is set for "today",
+// which asks for the latest rates rather than a historical day. Readings
+// holds both days a date such as "05/01/2022" can be when the order of day
+// and month is not known. Yearless is set for a date such as "Jan 5" whose
+// year was not written.
+type DateMatch struct {
+	Date     time.Time
+	Latest   bool
+	Yearless bool
+	Readings []string
+	Text     string
+	Pos      int
+	Length   int
+}
+
+// matchDate finds a date expression starting at pos, such as "yesterday",
+// "last Friday", "3 months ago", "Jan 5 2022", "5th of January",
+// "05/01/2022" or "end of last quarter", read relative to now.
+func matchDate(tokens []Token, pos int, now time.Time, order DateOrder) (DateMatch, bool, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	token := tokens[pos]
+	match := DateMatch{Pos: token.Pos, Length: 1}
+
+	if token.Kind == TokenDate {
+		date, readings, err := parseNumericDate(token.Text, order, today)
+		match.Date, match.Readings = date, readings
+		match.Text = token.Text
+		return match, true, err
+	}
+
+	word := func(offset int) string {
+		if pos+offset >= len(tokens) || tokens[pos+offset].Kind != TokenWord {
+			return ""
+		}
+		return strings.ToLower(tokens[pos+offset].Text)
+	}
+
+	switch first := word(0); {
+	case first == "today" || first == "now":
+		match.Date, match.Latest = today, true
+	case first == "yesterday":
+		match.Date = today.AddDate(0, 0, -1)
+	case first == "tomorrow":
+		match.Date = today.AddDate(0, 0, 1)
+	case first == "last":
+		weekday, ok := weekdayNames[word(1)]
+		if !ok {
+			return DateMatch{}, false, nil
+		}
+		days := (int(today.Weekday()) - int(weekday) + 6) % 7
+		match.Date, match.Length = today.AddDate(0, 0, -days-1), 2
+	case (first == "end" || first == "start" || first == "beginning") && word(1) == "of":
+		date, length, ok := periodBoundary(first == "end", word(2), word(3), today)
+		if !ok {
+			return DateMatch{}, false, nil
+		}
+		match.Date, match.Length = date, 2+length
+	case monthNames[first] != 0:
+		date, length, yearless, ok := monthFirstDate(tokens, pos, today)
+		if !ok {
+			return DateMatch{}, false, nil
+		}
+		match.Date, match.Length, match.Yearless = date, length, yearless
+	default:
+		date, length, yearless, ok := agoOrDayFirstDate(tokens, pos, today)
+		if !ok {
+			return DateMatch{}, false, nil
+		}
+		match.Date, match.Length, match.Yearless = date, length, yearless
+	}
+
+	match.Text = tokenText(tokens[pos : pos+match.Length])
+	return match, true, nil
+}
+
+// parseNumericDate reads a date in digits. When day and month could be
+// either way round and order does not say, both readings are returned. A
+// two-digit year is in this century unless that is after today's year, so
+// "05/01/99" is in 1999.
+func parseNumericDate(text string, order DateOrder, today time.Time) (time.Time, []string, error) {
+	if date, err := time.Parse("2006-01-02", text); err == nil {
+		return date, nil, nil
+	}
+	if parts := yearFirstPattern.FindStringSubmatch(text); parts != nil {
+		date, err := calendarDate(text, parts[1], parts[2], parts[3])
+		return date, nil, err
+	}
+
+	parts := numericDatePattern.FindStringSubmatch(text)
+	if parts == nil {
+		return time.Time{}, nil, fmt.Errorf("invalid date %q", text)
+	}
+	year := parts[3]
+	if len(year) == 2 {
+		year = fmt.Sprint(twoDigitYear(year, today))
+	}
+
+	dayFirst, dayFirstErr := calendarDate(text, year, parts[2], parts[1])
+	monthFirst, monthFirstErr := calendarDate(text, year, parts[1], parts[2])
+	switch {
+	case dayFirstErr != nil && monthFirstErr != nil:
+		return time.Time{}, nil, dayFirstErr
+	case dayFirstErr != nil || parts[1] == parts[2] || order == MonthFirst && monthFirstErr == nil:
+		return monthFirst, nil, nil
+	case monthFirstErr != nil || order == DayFirst:
+		return dayFirst, nil, nil
+	}
+	return time.Time{}, []string{dayFirst.Format(time.DateOnly), monthFirst.Format(time.DateOnly)}, nil
+}
+
+func twoDigitYear(text string, today time.Time) int {
+	year, _ := strconv.Atoi(text)
+	if year += 2000; year > today.Year() {
+		year -= 100
+	}
+	return year
+}
+
+func calendarDate(text, year, month, day string) (time.Time, error) {
+	date, err := time.Parse("2006-1-2", year+"-"+month+"-"+day)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", text)
+	}
+	return date, nil
+}
+
+// periodBoundary reads "[last|this] month" after "end of" or "start of",
+// returning the first or last day of that period and the words it took.
+func periodBoundary(end bool, first, second string, today time.Time) (time.Time, int, bool) {
+	offset, length := 0, 2
+	period := periodWords[second]
+	switch first {
+	case "last", "previous":
+		offset = -1
+	case "this", "the":
+	default:
+		period, length = periodWords[first], 1
+	}
+	if period == "" || period == "day" {
+		return time.Time{}, 0, false
+	}
+
+	var start time.Time
+	var span func(time.Time, int) time.Time
+	switch period {
+	case "week":
+		start = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		span = func(date time.Time, n int) time.Time { return date.AddDate(0, 0, 7*n) }
+	case "month":
+		start = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+		span = func(date time.Time, n int) time.Time { return date.AddDate(0, n, 0) }
+	case "quarter":
+		start = time.Date(today.Year(), (today.Month()-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+		span = func(date time.Time, n int) time.Time { return date.AddDate(0, 3*n, 0) }
+	case "year":
+		start = time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		span = func(date time.Time, n int) time.Time { return date.AddDate(n, 0, 0) }
+	}
+
+	start = span(start, offset)
+	if end {
+		return span(start, 1).AddDate(0, 0, -1), length, true
+	}
+	return start, length, true
+}
+
+// monthFirstDate reads "Jan 5", "January 5th, 2022" or "Jan 5 2022". A
+// date without a year is the most recent one, and is reported as yearless.
+func monthFirstDate(tokens []Token, pos int, today time.Time) (time.Time, int, bool, bool) {
+	month := monthNames[strings.ToLower(tokens[pos].Text)]
+	i := pos + 1
+	day, ok := dayNumber(tokens, i)
+	if !ok {
+		return time.Time{}, 0, false, false
+	}
+	i = skipOrdinal(tokens, i+1)
+
+	date, length, yearless := pastDate(today, month, day), i-pos, true
+	if year, next, ok := yearAfter(tokens, i); ok {
+		date, length, yearless = time.Date(year, month, day, 0, 0, 0, 0, time.UTC), next-pos, false
+	}
+	if date.Day() != day {
+		return time.Time{}, 0, false, false
+	}
+	return date, length, yearless, true
+}
+
+// agoOrDayFirstDate reads "3 months ago", "a week ago", "two days ago",
+// "5 January 2022" or "5th of Jan".
+func agoOrDayFirstDate(tokens []Token, pos int, today time.Time) (time.Time, int, bool, bool) {
+	count, length := 0, 0
+	switch token := tokens[pos]; {
+	case token.Kind == TokenNumber:
+		value, err := strconv.Atoi(token.Text)
+		if err != nil {
+			return time.Time{}, 0, false, false
+		}
+		count, length = value, 1
+	case isArticle(tokens, pos):
+		count, length = 1, 1
+	default:
+		amount, words, ok := scanNumberWords(tokens, pos)
+		if !ok || !amount.IsInt() {
+			return time.Time{}, 0, false, false
+		}
+		count, length = int(amount.Num().Int64()), words
+	}
+
+	i := pos + length
+	if i+1 < len(tokens) && tokens[i].Kind == TokenWord && strings.EqualFold(tokens[i+1].Text, "ago") {
+		if date, ok := countBackFrom(today, strings.ToLower(tokens[i].Text), count); ok {
+			return date, i + 2 - pos, false, true
+		}
+	}
+
+	if tokens[pos].Kind != TokenNumber {
+		return time.Time{}, 0, false, false
+	}
+	i = skipOrdinal(tokens, pos+1)
+	if i < len(tokens) && strings.EqualFold(tokens[i].Text, "of") {
+		i++
+	}
+	if i >= len(tokens) || tokens[i].Kind != TokenWord || monthNames[strings.ToLower(tokens[i].Text)] == 0 {
+		return time.Time{}, 0, false, false
+	}
+	month := monthNames[strings.ToLower(tokens[i].Text)]
+	i++
+
+	date, yearless := pastDate(today, month, count), true
+	if year, next, ok := yearAfter(tokens, i); ok {
+		date, i, yearless = time.Date(year, month, count, 0, 0, 0, 0, time.UTC), next, false
+	}
+	if count < 1 || date.Day() != count {
+		return time.Time{}, 0, false, false
+	}
+	return date, i - pos, yearless, true
+}
+
+// pastDate is the most recent month and day on or before today.
+func pastDate(today time.Time, month time.Month, day int) time.Time {
+	date := time.Date(today.Year(), month, day, 0, 0, 0, 0, time.UTC)
+	if date.After(today) {
+		date = time.Date(today.Year()-1, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	return date
+}
+
+func dayNumber(tokens []Token, pos int) (int, bool) {
+	if pos >= len(tokens) || tokens[pos].Kind != TokenNumber {
+		return 0, false
+	}
+	day, err := strconv.Atoi(tokens[pos].Text)
+	return day, err == nil && day >= 1 && day <= 31
+}
+
+func skipOrdinal(tokens []Token, pos int) int {
+	if pos < len(tokens) && tokens[pos].Kind == TokenWord && slices.Contains(ordinalSuffixes, strings.ToLower(tokens[pos].Text)) {
+		return pos + 1
+	}
+	return pos
+}
+
+// yearAfter reads a four-digit year at pos, after an optional comma.
+func yearAfter(tokens []Token, pos int) (int, int, bool) {
+	if pos < len(tokens) && tokens[pos].Kind == TokenComma {
+		pos++
+	}
+	if pos >= len(tokens) || tokens[pos].Kind != TokenNumber || len(tokens[pos].Text) != 4 {
+		return 0, 0, false
+	}
+	year, err := strconv.Atoi(tokens[pos].Text)
+	return year, pos + 1, err == nil
+}
+
+// validateRateDate checks that rates can exist for date: not before the
+// provider's history starts, and not after today.
+func validateRateDate(date time.Time, now time.Time) error {
+	if date.Format(time.DateOnly) < earliestRateDate {
+		return fmt.Errorf("no rates before %s", earliestRateDate)
+	}
+	if date.Format(time.DateOnly) > now.Format(time.DateOnly) {
+		return fmt.Errorf("%s is in the future", date.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// rangeSeparators join the two ends of a range, as in "from 2023-01-01 to
+// 2023-03-31" or "between Jan 1 and Mar 31".
+var rangeSeparators = []string{"to", "until", "till", "through", "and"}
+
+// RangeMatch is a run of tokens naming a span of days.
+type RangeMatch struct {
+	Start  DateMatch
+	End    DateMatch
+	Text   string
+	Pos    int
+	Length int
+
+	// Relative is set for spans counted back from today, such as "the
+	// last 30 days".
+	Relative bool
+}
+
+// matchDateRange finds a range starting at pos: two dates joined by "to",
+// "until" or "and", optionally after "from" or "between", the previous
+// calendar period such as "last month", or a span counted back from today
+// such as "last 30 days" or "past three months".
+func matchDateRange(tokens []Token, pos int, now time.Time, order DateOrder) (RangeMatch, bool, error) {
+	token := tokens[pos]
+	match := RangeMatch{Pos: token.Pos}
+	word := strings.ToLower(token.Text)
+
+	if token.Kind == TokenWord && (word == "last" || word == "past") {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if start, end, ok := lastPeriod(tokens, pos, today); ok {
+			match.Start = DateMatch{Date: start, Pos: token.Pos}
+			match.End = DateMatch{Date: end, Pos: token.Pos}
+			match.Length, match.Relative = 2, true
+			match.Text = tokenText(tokens[pos : pos+match.Length])
+			return match, true, nil
+		}
+
+		start, length, ok := countBack(tokens, pos+1, today)
+		if !ok {
+			return RangeMatch{}, false, nil
+		}
+		match.Start = DateMatch{Date: start, Pos: token.Pos}
+		match.End = DateMatch{Date: today, Latest: true, Pos: token.Pos}
+		match.Length, match.Relative = 1+length, true
+		match.Text = tokenText(tokens[pos : pos+match.Length])
+		return match, true, nil
+	}
+
+	i := pos
+	if token.Kind == TokenWord && (word == "from" || word == "between") {
+		i++
+	}
+	if i >= len(tokens) {
+		return RangeMatch{}, false, nil
+	}
+	start, ok, err := matchDate(tokens, i, now, order)
+	if !ok || start.Latest {
+		return RangeMatch{}, false, nil
+	}
+	i += start.Length
+	if i+1 >= len(tokens) || tokens[i].Kind != TokenWord || !slices.Contains(rangeSeparators, strings.ToLower(tokens[i].Text)) {
+		return RangeMatch{}, false, nil
+	}
+	end, ok, endErr := matchDate(tokens, i+1, now, order)
+	if !ok {
+		return RangeMatch{}, false, nil
+	}
+
+	start, end = shareYear(start, end)
+	match.Start, match.End = start, end
+	match.Length = i + 1 + end.Length - pos
+	match.Text = tokenText(tokens[pos : pos+match.Length])
+	if err == nil {
+		err = endErr
+	}
+	return match, true, err
+}
+
+// shareYear gives an end of a range written without a year the year of the
+// other end, so "Jan 1 to Jan 5 2023" starts in 2023 and "Dec 20 2022 to
+// Jan 5" ends in 2023, moving it a year when that would put the ends the
+// wrong way round. When neither end has a year, the start is the most
+// recent such day and the end follows it.
+func shareYear(start, end DateMatch) (DateMatch, DateMatch) {
+	switch {
+	case start.Yearless && !end.Yearless:
+		start.Date = inYear(start.Date, end.Date.Year())
+		if start.Date.After(end.Date) {
+			start.Date = inYear(start.Date, end.Date.Year()-1)
+		}
+	case end.Yearless:
+		end.Date = inYear(end.Date, start.Date.Year())
+		if end.Date.Before(start.Date) {
+			end.Date = inYear(end.Date, start.Date.Year()+1)
+		}
+	}
+	return start, end
+}
+
+// inYear moves date to year, leaving it where it was when the day does not
+// exist in that year, as 29 February mostly does not.
+func inYear(date time.Time, year int) time.Time {
+	moved := time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if moved.Day() != date.Day() {
+		return date
+	}
+	return moved
+}
+
+// lastPeriod reads "last week", "last month", "last quarter" or "last
+// year" at pos, returning the first and last day of that calendar period
+// before the current one.
+func lastPeriod(tokens []Token, pos int, today time.Time) (time.Time, time.Time, bool) {
+	if pos+1 >= len(tokens) || tokens[pos+1].Kind != TokenWord {
+		return time.Time{}, time.Time{}, false
+	}
+	first, second := strings.ToLower(tokens[pos].Text), strings.ToLower(tokens[pos+1].Text)
+	if first != "last" || periodWords[second] != second {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start, _, ok := periodBoundary(false, first, second, today)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	end, _, _ := periodBoundary(true, first, second, today)
+	return start, end, true
+}
+
+// countBack reads "30 days", "three months" or "week" at pos, returning
+// the first day of the span that long which ends today, so "30 days"
+// starts 29 days ago, and the tokens it took.
+func countBack(tokens []Token, pos int, today time.Time) (time.Time, int, bool) {
+	count, length := 1, 0
+	if pos < len(tokens) && tokens[pos].Kind == TokenNumber {
+		value, err := strconv.Atoi(tokens[pos].Text)
+		if err != nil {
+			return time.Time{}, 0, false
+		}
+		count, length = value, 1
+	} else if amount, words, ok := scanNumberWords(tokens, pos); ok && amount.IsInt() {
+		count, length = int(amount.Num().Int64()), words
+	}
+
+	i := pos + length
+	if i >= len(tokens) || tokens[i].Kind != TokenWord || count < 1 {
+		return time.Time{}, 0, false
+	}
+	date, ok := countBackFrom(today, strings.ToLower(tokens[i].Text), count)
+	return date.AddDate(0, 0, 1), length + 1, ok
+}
+
+// countBackFrom is the day count periods, such as "days" or "months",
+// before today.
+func countBackFrom(today time.Time, period string, count int) (time.Time, bool) {
+	switch periodWords[period] {
+	case "day":
+		return today.AddDate(0, 0, -count), true
+	case "week":
+		return today.AddDate(0, 0, -7*count), true
+	case "month":
+		return today.AddDate(0, -count, 0), true
+	case "quarter":
+		return today.AddDate(0, -3*count, 0), true
+	case "year":
+		return today.AddDate(-count, 0, 0), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQueryDates(t *testing.T) {
+	// A Wednesday.
+	now := time.Date(2023, time.July, 12, 15, 30, 0, 0, time.UTC)
+	options := ParseOptions{Locale: parseLocale("en-GB"), Now: func() time.Time { return now }}
+
+	cases := []struct {
+		Input string
+		Date  string
+	}{
+		{"100 USD to EUR yesterday", "2023-07-11"},
+		{"100 USD to EUR last Friday", "2023-07-07"},
+		{"100 USD to EUR last wednesday", "2023-07-05"},
+		{"100 USD to EUR 3 months ago", "2023-04-12"},
+		{"100 USD to EUR three weeks ago", "2023-06-21"},
+		{"100 USD to EUR a year ago", "2022-07-12"},
+		{"100 USD to EUR on Jan 5 2022", "2022-01-05"},
+		{"100 USD to EUR on January 5th, 2022", "2022-01-05"},
+		{"100 USD to EUR on 5th of March", "2023-03-05"},
+		{"100 USD to EUR on 5 December", "2022-12-05"},
+		{"100 USD to EUR on 05/01/2022", "2022-01-05"},
+		{"100 USD to EUR on 5.1.2022", "2022-01-05"},
+		{"100 USD to EUR on 2022/01/05", "2022-01-05"},
+		{"100 USD to EUR on 25/12/2022", "2022-12-25"},
+		{"100 USD to EUR on 25/12/22", "2022-12-25"},
+		{"100 USD to EUR on 25/12/99", "1999-12-25"},
+		{"100 USD to EUR at the end of last quarter", "2023-06-30"},
+		{"100 USD to EUR at the end of last month", "2023-06-30"},
+		{"100 USD to EUR at the start of this year", "2023-01-01"},
+		{"100 USD to EUR at the end of last week", "2023-07-09"},
+		{"100 USD to EUR on 2023-06-30", "2023-06-30"},
+		{"100 USD to EUR today", ""},
+	}
+
+	for _, testCase := range cases {
+		query, err := parseQueryWith(testCase.Input, options)
+		if err != nil {
+			t.Errorf("FAILED: parseQueryWith(%q) returned %v", testCase.Input, err)
+			continue
+		}
+		if query.Date != testCase.Date || query.Source != "USD" || !slices.Equal(query.Targets, []string{"EUR"}) {
+			t.Errorf("FAILED: %q read as %s to %v on %q, expected %q", testCase.Input, query.Source, query.Targets, query.Date, testCase.Date)
+		}
+	}
+
+	options.DateOrder = MonthFirst
+	if query, err := parseQueryWith("100 USD to EUR on 05/01/2022", options); err != nil || query.Date != "2022-05-01" {
+		t.Errorf("FAILED: Expected month first to read 05/01/2022 as 2022-05-01, got %q (%v)", query.Date, err)
+	}
+}
+
+func TestParseQueryDateErrors(t *testing.T) {
+	now := time.Date(2023, time.July, 12, 0, 0, 0, 0, time.UTC)
+	options := ParseOptions{Now: func() time.Time { return now }}
+
+	cases := []struct {
+		Input   string
+		Message string
+	}{
+		{"100 USD to EUR tomorrow", "2023-07-13 is in the future"},
+		{"100 USD to EUR on 1998-12-31", "no rates before 1999-01-01"},
+		{"100 USD to EUR 30 years ago", "no rates before 1999-01-01"},
+		{"100 USD to EUR on 31/02/2022", `invalid date "31/02/2022"`},
+		{"100 USD to EUR yesterday on 2023-01-01", "only one date can be given"},
+	}
+
+	for _, testCase := range cases {
+		_, err := parseQueryWith(testCase.Input, options)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || !strings.Contains(parseErr.Message, testCase.Message) {
+			t.Errorf("FAILED: parseQueryWith(%q) returned %v, expected %q", testCase.Input, err, testCase.Message)
+		}
+	}
+
+	_, err := parseQueryWith("100 USD to EUR on 05/01/2022", options)
+	var ambiguity *AmbiguityError
+	if !errors.As(err, &ambiguity) || ambiguity.Kind != AmbiguousDate || !slices.Equal(ambiguity.Candidates, []string{"2022-01-05", "2022-05-01"}) {
+		t.Fatalf("FAILED: Expected 05/01/2022 to be ambiguous without a locale, got %v", err)
+	}
+	options.Resolutions = map[int]string{ambiguity.Pos: "2022-05-01"}
+	if query, err := parseQueryWith("100 USD to EUR on 05/01/2022", options); err != nil || query.Date != "2022-05-01" {
+		t.Errorf("FAILED: Expected the answer to settle 05/01/2022, got %q (%v)", query.Date, err)
+	}
+}
@@ -5,18 +5,23 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"sync"
 
 	"github.com/joho/godotenv"
 )
 
-func getEnvVar(key string) string {
-
+// loadDotenv reads .env into the environment the first time a variable is
+// looked up. Variables already set in the environment take precedence.
+var loadDotenv = sync.OnceFunc(func() {
 	err := godotenv.Load(".env")
 
 	if err != nil {
 		log.Fatalf("Error loading .env file")
 	}
+})
 
+func getEnvVar(key string) string {
+	loadDotenv()
 	return os.Getenv(key)
 }
 
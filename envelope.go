@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// cacheSchemaVersion is the version written by newCacheEnvelope. Bump it
+// whenever CacheEnvelope changes shape and register a migration from the
+// previous version in cacheMigrations.
+const cacheSchemaVersion = 3
+
+const defaultProvider = "openexchangerates"
+
+var errUnsupportedSchema = errors.New("unsupported cache schema version")
+var errContentHashMismatch = errors.New("cache content hash mismatch")
+
+// CacheEnvelope is the stored form of a rate table. Besides the rates it
+// records where they came from and when, so cached data can be traced back
+// to the upstream response that produced it.
+type CacheEnvelope struct {
+	SchemaVersion int            `json:"schema_version"`
+	Provider      string         `json:"provider"`
+	Base          string         `json:"base"`
+	FetchedAt     int64          `json:"fetched_at"`
+	Timestamp     int64          `json:"timestamp"`
+	License       string         `json:"license,omitempty"`
+	Disclaimer    string         `json:"disclaimer,omitempty"`
+	ContentHash   string         `json:"content_hash"`
+	Rates         map[string]any `json:"rates"`
+
+	// Pairs holds direct quotes, keyed "FROM/TO", for providers that
+	// publish them alongside or instead of a base-currency table.
+	Pairs map[string]float64 `json:"pairs,omitempty"`
+}
+
+// cacheMigrations upgrades a raw entry of the keyed version to the next one.
+var cacheMigrations = map[int]func(map[string]json.RawMessage) error{
+	1: migrateCacheV1,
+	2: migrateCacheV2,
+}
+
+func newCacheEnvelope(latest Latest, provider string, fetchedAt int64) CacheEnvelope {
+	base := latest.Base
+	if base == "" {
+		base = "USD"
+	}
+
+	envelope := CacheEnvelope{
+		SchemaVersion: cacheSchemaVersion,
+		Provider:      provider,
+		Base:          base,
+		FetchedAt:     fetchedAt,
+		Timestamp:     latest.Timestamp,
+		License:       latest.License,
+		Disclaimer:    latest.Disclaimer,
+		Rates:         latest.Rates,
+	}
+	envelope.ContentHash = contentHash(envelope.Base, envelope.Timestamp, envelope.Rates, envelope.Pairs)
+
+	return envelope
+}
+
+func (envelope CacheEnvelope) latest() Latest {
+	return Latest{
+		Disclaimer: envelope.Disclaimer,
+		License:    envelope.License,
+		Timestamp:  envelope.Timestamp,
+		Base:       envelope.Base,
+		Rates:      envelope.Rates,
+	}
+}
+
+// decodeCacheEnvelope reads a cache entry of any known schema version,
+// migrating older entries to the current one. Entries written by a newer
+// version of the program are rejected rather than guessed at.
+func decodeCacheEnvelope(data []byte) (CacheEnvelope, error) {
+	var envelope CacheEnvelope
+	var raw map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return envelope, fmt.Errorf("failed to parse cache entry: %w", err)
+	}
+
+	version := 1
+	if rawVersion, ok := raw["schema_version"]; ok {
+		if err := json.Unmarshal(rawVersion, &version); err != nil {
+			return envelope, fmt.Errorf("failed to parse cache schema version: %w", err)
+		}
+	}
+
+	if version > cacheSchemaVersion {
+		return envelope, fmt.Errorf("%w: %d", errUnsupportedSchema, version)
+	}
+
+	for version < cacheSchemaVersion {
+		migrate, ok := cacheMigrations[version]
+		if !ok {
+			return envelope, fmt.Errorf("%w: no migration from %d", errUnsupportedSchema, version)
+		}
+		if err := migrate(raw); err != nil {
+			return envelope, fmt.Errorf("failed to migrate cache entry from version %d: %w", version, err)
+		}
+		version++
+		raw["schema_version"], _ = json.Marshal(version)
+	}
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return envelope, err
+	}
+	if err := json.Unmarshal(migrated, &envelope); err != nil {
+		return envelope, fmt.Errorf("failed to parse cache entry: %w", err)
+	}
+
+	if envelope.ContentHash != contentHash(envelope.Base, envelope.Timestamp, envelope.Rates, envelope.Pairs) {
+		return envelope, errContentHashMismatch
+	}
+
+	return envelope, nil
+}
+
+// migrateCacheV1 upgrades a bare Latest, as stored before envelopes existed.
+// Version 1 entries never recorded when they were fetched, so the upstream
+// timestamp stands in for it.
+func migrateCacheV1(raw map[string]json.RawMessage) error {
+	var latest Latest
+	legacy, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(legacy, &latest); err != nil {
+		return err
+	}
+	if latest.Rates == nil {
+		return errors.New("cache entry has no rates")
+	}
+
+	envelope := newCacheEnvelope(latest, defaultProvider, latest.Timestamp)
+	upgraded, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	clear(raw)
+	return json.Unmarshal(upgraded, &raw)
+}
+
+// migrateCacheV2 upgrades an entry written before direct pair quotes were
+// recorded. It has none, and its content hash leaves them out, so the entry
+// only needs its version raising.
+func migrateCacheV2(raw map[string]json.RawMessage) error {
+	if _, ok := raw["rates"]; !ok {
+		return errors.New("cache entry has no rates")
+	}
+	return nil
+}
+
+func contentHash(base string, timestamp int64, rates map[string]any, pairs map[string]float64) string {
+	// json.Marshal sorts map keys, which keeps the hash stable across writes.
+	// Pairs are left out when empty so entries written before they existed
+	// still verify.
+	content, _ := json.Marshal(struct {
+		Base      string             `json:"base"`
+		Timestamp int64              `json:"timestamp"`
+		Rates     map[string]any     `json:"rates"`
+		Pairs     map[string]float64 `json:"pairs,omitempty"`
+	}{base, timestamp, rates, pairs})
+
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
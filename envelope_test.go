@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDecodeLegacyCacheEntry(t *testing.T) {
+	legacy := []byte(`{"timestamp": 1688169597, "rates": {"EUR": 0.916, "USD": 1}}`)
+
+	envelope, err := decodeCacheEnvelope(legacy)
+	if err != nil {
+		t.Fatalf("FAILED: decodeCacheEnvelope returned %v", err)
+	}
+
+	if envelope.SchemaVersion != cacheSchemaVersion {
+		t.Errorf("FAILED: expected schema version %d, got %d", cacheSchemaVersion, envelope.SchemaVersion)
+	}
+	if envelope.Provider != defaultProvider || envelope.Base != "USD" {
+		t.Errorf("FAILED: expected %s/USD, got %s/%s", defaultProvider, envelope.Provider, envelope.Base)
+	}
+	if envelope.FetchedAt != 1688169597 || envelope.Rates["EUR"] != 0.916 {
+		t.Errorf("FAILED: migrated envelope lost data: %+v", envelope)
+	}
+}
+
+func TestDecodeVersion2CacheEntry(t *testing.T) {
+	rates := map[string]any{"EUR": 0.916, "USD": 1.0}
+	version2, _ := json.Marshal(map[string]any{
+		"schema_version": 2,
+		"provider":       defaultProvider,
+		"base":           "USD",
+		"fetched_at":     1688170000,
+		"timestamp":      1688169597,
+		"content_hash":   contentHash("USD", 1688169597, rates, nil),
+		"rates":          rates,
+	})
+
+	envelope, err := decodeCacheEnvelope(version2)
+	if err != nil {
+		t.Fatalf("FAILED: decodeCacheEnvelope returned %v", err)
+	}
+	if envelope.SchemaVersion != cacheSchemaVersion || envelope.Pairs != nil || envelope.FetchedAt != 1688170000 {
+		t.Errorf("FAILED: Expected a version %d envelope without pairs, got %+v", cacheSchemaVersion, envelope)
+	}
+}
+
+func TestCacheEnvelopeRoundTrip(t *testing.T) {
+	latest := Latest{Timestamp: 1688169597, Base: "EUR", License: "license", Rates: map[string]any{"USD": 1.09}}
+	envelope := newCacheEnvelope(latest, "ecb", 1688170000)
+
+	data, _ := json.Marshal(envelope)
+	decoded, err := decodeCacheEnvelope(data)
+	if err != nil {
+		t.Fatalf("FAILED: decodeCacheEnvelope returned %v", err)
+	}
+	if decoded.ContentHash != envelope.ContentHash || decoded.latest().Base != "EUR" || decoded.Provider != "ecb" {
+		t.Errorf("FAILED: expected %+v, got %+v", envelope, decoded)
+	}
+}
+
+func TestDecodeRejectsBadCacheEntries(t *testing.T) {
+	envelope := newCacheEnvelope(Latest{Timestamp: 1, Rates: map[string]any{"EUR": 0.9}}, defaultProvider, 1)
+
+	envelope.Rates["EUR"] = 0.8
+	tampered, _ := json.Marshal(envelope)
+	if _, err := decodeCacheEnvelope(tampered); !errors.Is(err, errContentHashMismatch) {
+		t.Errorf("FAILED: expected hash mismatch, got %v", err)
+	}
+
+	future := []byte(`{"schema_version": 99, "rates": {}}`)
+	if _, err := decodeCacheEnvelope(future); !errors.Is(err, errUnsupportedSchema) {
+		t.Errorf("FAILED: expected unsupported schema, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownCurrency   = errors.New("unknown currency")
+	ErrMissingRate       = errors.New("missing exchange rate")
+	ErrNonPositiveAmount = errors.New("amount must be positive")
+	ErrOverflow          = errors.New("amount out of range")
+	ErrUnknownProfile    = errors.New("unknown pricing profile")
+	ErrFeesExceedAmount  = errors.New("fees exceed amount")
+	ErrUnreachableTarget = errors.New("target amount cannot be reached")
+	ErrUnknownOverlay    = errors.New("unknown rate overlay")
+	ErrNotInCirculation  = errors.New("currency not in circulation on that date")
+	ErrInvalidUnit       = errors.New("units of weight only apply to precious metals")
+)
+
+// conversionErrorCodes are the machine-readable codes reported by the API.
+var conversionErrorCodes = map[error]string{
+	ErrUnknownCurrency:   "unknown_currency",
+	ErrMissingRate:       "missing_rate",
+	ErrNonPositiveAmount: "non_positive_amount",
+	ErrOverflow:          "overflow",
+	ErrUnknownProfile:    "unknown_profile",
+	ErrFeesExceedAmount:  "fees_exceed_amount",
+	ErrUnreachableTarget: "unreachable_target",
+	ErrUnknownOverlay:    "unknown_overlay",
+	ErrNotInCirculation:  "not_in_circulation",
+	ErrInvalidUnit:       "invalid_unit",
+}
+
+// ConversionError explains why convert could not produce a result. Kind is
+// one of the Err sentinels above, so callers can test it with errors.Is.
+type ConversionError struct {
+	Kind     error
+	Currency string
+	Detail   string
+}
+
+func (e *ConversionError) Error() string {
+	message := e.Kind.Error()
+	if e.Currency != "" {
+		message = fmt.Sprintf("%s: %s", message, e.Currency)
+	}
+	if e.Detail != "" {
+		message = fmt.Sprintf("%s (%s)", message, e.Detail)
+	}
+	return message
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Kind
+}
+
+func (e *ConversionError) Code() string {
+	return conversionErrorCodes[e.Kind]
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// keyboardRows is the QWERTY layout typos are measured against. Each row
+// sits half a key to the right of the one above, so a key touches the
+// keys at the same and the next column of the row above.
+var keyboardRows = []string{"QWERTYUIOP", "ASDFGHJKL", "ZXCVBNM"}
+
+// majorCurrencies break ties between equally close suggestions, the most
+// widely traded first.
+var majorCurrencies = []string{"USD", "EUR", "JPY", "GBP", "CNY", "AUD", "CAD", "CHF", "HKD", "SGD", "SEK", "KRW", "NOK", "NZD", "INR", "MXN"}
+
+// Costs of the edits a typo is made of. Slips of the finger, such as
+// swapping two letters, hitting the key beside the one meant or pressing a
+// key twice, cost half as much as any other change.
+const (
+	typoSlipCost = 0.5
+	typoEditCost = 1.0
+)
+
+// Limits on how close a word must be to a code. A word is corrected
+// without asking only when its closest code is a single slip away and the
+// next closest is at least a slip further; anything else is a suggestion.
+const (
+	maxSuggestionDistance = typoEditCost
+	maxCorrectionDistance = typoSlipCost
+	maxSuggestions        = 3
+)
+
+// reservedCodes are ISO 4217 codes that name no currency, such as XXX for
+// "no currency" and XTS for testing. They are never read as typos.
+var reservedCodes = []string{"XXX", "XTS", "XBA", "XBB", "XBC", "XBD", "XSU", "XUA"}
+
+// CurrencySuggestion is a code a mistyped word may have meant, and how far
+// the word is from it.
+type CurrencySuggestion struct {
+	Code     string
+	Distance float64
+}
+
+// suggestCurrencies ranks the codes in the registry that word may be a
+// typo of, closest first.
+func suggestCurrencies(word string) []CurrencySuggestion {
+	if isReservedCode(word) || len(word) < 2 || len(word) > 5 || strings.IndexFunc(word, func(char rune) bool { return !unicode.IsLetter(char) }) >= 0 {
+		return nil
+	}
+
+	var suggestions []CurrencySuggestion
+	for _, currency := range listCurrencies() {
+		if currency.Status == "historic" {
+			continue
+		}
+		if distance := typoDistance(strings.ToUpper(word), currency.Code); distance <= maxSuggestionDistance {
+			suggestions = append(suggestions, CurrencySuggestion{Code: currency.Code, Distance: distance})
+		}
+	}
+	slices.SortStableFunc(suggestions, func(a, b CurrencySuggestion) int {
+		if a.Distance != b.Distance {
+			if a.Distance < b.Distance {
+				return -1
+			}
+			return 1
+		}
+		return majorRank(a.Code) - majorRank(b.Code)
+	})
+	return suggestions[:min(len(suggestions), maxSuggestions)]
+}
+
+func majorRank(code string) int {
+	if rank := slices.Index(majorCurrencies, code); rank >= 0 {
+		return rank
+	}
+	return len(majorCurrencies)
+}
+
+func isReservedCode(word string) bool {
+	return slices.Contains(reservedCodes, strings.ToUpper(word))
+}
+
+// correction is the code word is confidently a typo of, if there is one.
+func correction(word string, suggestions []CurrencySuggestion) (string, bool) {
+	if len(suggestions) == 0 || isReservedCode(word) {
+		return "", false
+	}
+	best := suggestions[0]
+	if best.Distance > maxCorrectionDistance {
+		return "", false
+	}
+	if len(suggestions) > 1 && suggestions[1].Distance-best.Distance < typoSlipCost {
+		return "", false
+	}
+	return best.Code, true
+}
+
+// typoDistance is the cost of the edits that turn typed into intended,
+// counting slips of the finger as cheaper than other changes. Both are
+// expected in upper case.
+func typoDistance(typed, intended string) float64 {
+	a, b := []rune(typed), []rune(intended)
+	distance := make([][]float64, len(a)+1)
+	for i := range distance {
+		distance[i] = make([]float64, len(b)+1)
+		distance[i][0] = float64(i) * typoEditCost
+	}
+	for j := range distance[0] {
+		distance[0][j] = float64(j) * typoEditCost
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			substitution := typoEditCost
+			switch {
+			case a[i-1] == b[j-1]:
+				substitution = 0
+			case keysAdjacent(a[i-1], b[j-1]):
+				substitution = typoSlipCost
+			}
+
+			deletion := typoEditCost
+			if i > 1 && a[i-1] == a[i-2] {
+				deletion = typoSlipCost
+			}
+
+			distance[i][j] = min(
+				distance[i-1][j-1]+substitution,
+				distance[i-1][j]+deletion,
+				distance[i][j-1]+typoEditCost,
+			)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				distance[i][j] = min(distance[i][j], distance[i-2][j-2]+typoSlipCost)
+			}
+		}
+	}
+	return distance[len(a)][len(b)]
+}
+
+// keysAdjacent reports whether two letters are beside each other on the
+// keyboard.
+func keysAdjacent(a, b rune) bool {
+	rowA, columnA := keyPosition(a)
+	rowB, columnB := keyPosition(b)
+	if rowA < 0 || rowB < 0 {
+		return false
+	}
+	if rowA > rowB {
+		rowA, columnA, rowB, columnB = rowB, columnB, rowA, columnA
+	}
+	switch rowB - rowA {
+	case 0:
+		return columnA-columnB == 1 || columnB-columnA == 1
+	case 1:
+		return columnA == columnB || columnA == columnB+1
+	}
+	return false
+}
+
+func keyPosition(char rune) (int, int) {
+	for row, keys := range keyboardRows {
+		if column := strings.IndexRune(keys, char); column >= 0 {
+			return row, column
+		}
+	}
+	return -1, -1
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseQueryTypos(t *testing.T) {
+	cases := []struct {
+		Input   string
+		Source  string
+		Targets []string
+		Notes   []string
+	}{
+		{"100 EURR to USD", "EUR", []string{"USD"}, []string{`"EURR" corrected to EUR`}},
+		{"100 UDS to EUR", "USD", []string{"EUR"}, []string{`"UDS" corrected to USD`}},
+		{"5000 JYP in GBP", "JPY", []string{"GBP"}, []string{`"JYP" corrected to JPY`}},
+		{"100 usd to gpb", "USD", []string{"GBP"}, []string{`"gpb" corrected to GBP`}},
+		{"What is 100 JPYY in CHFF?", "JPY", []string{"CHF"}, []string{`"JPYY" corrected to JPY`, `"CHFF" corrected to CHF`}},
+	}
+
+	for _, testCase := range cases {
+		query, err := parseQuery(testCase.Input)
+		if err != nil {
+			t.Errorf("FAILED: parseQuery(%q) returned %v", testCase.Input, err)
+			continue
+		}
+		var notes []string
+		for _, interpretation := range query.Interpretations {
+			notes = append(notes, interpretation.String())
+		}
+		if query.Source != testCase.Source || !slices.Equal(query.Targets, testCase.Targets) || !slices.Equal(notes, testCase.Notes) {
+			t.Errorf("FAILED: %q read as %s to %v %v, expected %s to %v %v", testCase.Input, query.Source, query.Targets, notes, testCase.Source, testCase.Targets, testCase.Notes)
+		}
+	}
+}
+
+func TestParseQueryTypoSuggestions(t *testing.T) {
+	cases := []struct {
+		Input       string
+		Position    int
+		Suggestions []string
+	}{
+		// Only a single slip of the finger is corrected, however the word
+		// is written, and "CAF" is as close to CAD as to XAF.
+		{"100 eurx to USD", 4, []string{"EUR"}},
+		{"100 EURX to USD", 4, []string{"EUR"}},
+		{"100 ABC to USD", 4, []string{"SBD", "SVC"}},
+		{"100 USD to CAF", 11, []string{"CAD", "XAF", "CHF"}},
+		{"100 USD to widgets", 11, nil},
+		// XXX is ISO 4217's code for no currency, not a typo of XCD.
+		{"100 XXX to USD", 4, nil},
+		{"100 USD to XTS", 11, nil},
+	}
+
+	for _, testCase := range cases {
+		_, err := parseQuery(testCase.Input)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Pos != testCase.Position || !slices.Equal(parseErr.Suggestions, testCase.Suggestions) {
+			t.Errorf("FAILED: parseQuery(%q) Expected suggestions %v at %d, got %v", testCase.Input, testCase.Suggestions, testCase.Position, err)
+		}
+	}
+}
+
+func TestTypoDistance(t *testing.T) {
+	cases := []struct {
+		Typed    string
+		Intended string
+		Distance float64
+	}{
+		{"USD", "USD", 0},
+		{"UDS", "USD", 0.5},
+		{"EURR", "EUR", 0.5},
+		{"EUE", "EUR", 0.5},
+		{"EUX", "EUR", 1},
+		{"EURX", "EUR", 1},
+		{"XYZ", "USD", 3},
+	}
+
+	for _, testCase := range cases {
+		if distance := typoDistance(testCase.Typed, testCase.Intended); distance != testCase.Distance {
+			t.Errorf("FAILED: typoDistance(%q, %q) = %v, expected %v", testCase.Typed, testCase.Intended, distance, testCase.Distance)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+)
+
+// maxRouteHops bounds how many pairs a routed conversion may chain.
+const maxRouteHops = 4
+
+// RoutingMode picks between paths through the rate graph: the one that
+// delivers the most of the target asset, or the one with the fewest hops.
+type RoutingMode string
+
+const (
+	RouteBest     RoutingMode = "best"
+	RouteShortest RoutingMode = "shortest"
+)
+
+// PairQuote is a price published for a single pair, as crypto exchanges and
+// internal desks quote them. Bid is what one unit of From sells for in To
+// and Ask what it costs to buy; Rate is a mid price for sources without
+// sides. At least one of the three must be set.
+type PairQuote struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Bid    string `json:"bid,omitempty"`
+	Ask    string `json:"ask,omitempty"`
+	Rate   string `json:"rate,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+// RateGraph holds directed edges between assets, each priced at what one
+// unit of the first asset actually buys of the second.
+type RateGraph struct {
+	edges map[string][]graphEdge
+}
+
+type graphEdge struct {
+	to   string
+	rate *big.Rat
+}
+
+// RatePath is a route through the graph and the rate it compounds to.
+type RatePath struct {
+	Assets []string
+	Rate   *big.Rat
+}
+
+func (path RatePath) String() string {
+	return strings.Join(path.Assets, "→")
+}
+
+// loadPairQuotes reads PAIR_QUOTES_FILE, which is only read again once it
+// changes on disk, as every token of a query may be checked against it.
+func loadPairQuotes() ([]PairQuote, error) {
+	fileName := getEnvVar("PAIR_QUOTES_FILE")
+	if fileName == "" {
+		return nil, nil
+	}
+
+	quotes, err := loadConfigFile(fileName, func(data []byte) ([]PairQuote, error) {
+		var quotes []PairQuote
+		if err := json.Unmarshal(data, &quotes); err != nil {
+			return nil, fmt.Errorf("failed to parse pair quotes file: %w", err)
+		}
+		for _, quote := range quotes {
+			if err := quote.validate(); err != nil {
+				return nil, err
+			}
+		}
+		return quotes, nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load pair quotes file: %w", err)
+	}
+	return quotes, nil
+}
+
+// isQuotedAsset reports whether code is traded in the pair quotes file, so
+// assets outside ISO 4217 such as ADA or USDT can still be converted.
+func isQuotedAsset(code string) bool {
+	quotes, err := loadPairQuotes()
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(quotes, func(quote PairQuote) bool {
+		return quote.From == code || quote.To == code
+	})
+}
+
+func (quote PairQuote) validate() error {
+	if quote.From == "" || quote.To == "" || quote.From == quote.To {
+		return fmt.Errorf("invalid quoted pair %s/%s", quote.From, quote.To)
+	}
+
+	prices := 0
+	for _, price := range []string{quote.Bid, quote.Ask, quote.Rate} {
+		if price == "" {
+			continue
+		}
+		value, _, err := parseDecimal(price)
+		if err != nil || value.Sign() <= 0 {
+			return fmt.Errorf("invalid price %q for %s/%s", price, quote.From, quote.To)
+		}
+		prices++
+	}
+	if prices == 0 {
+		return fmt.Errorf("no price for %s/%s", quote.From, quote.To)
+	}
+
+	bid, ask := quote.sell(), quote.buy()
+	if bid.Cmp(ask) > 0 {
+		return fmt.Errorf("bid above ask for %s/%s", quote.From, quote.To)
+	}
+	return nil
+}
+
+// sell is what one From fetches in To: the bid, or the best price there is
+// when no bid was quoted.
+func (quote PairQuote) sell() *big.Rat {
+	return firstPrice(quote.Bid, quote.Rate, quote.Ask)
+}
+
+// buy is what one From costs in To: the ask, or the best price there is
+// when no ask was quoted.
+func (quote PairQuote) buy() *big.Rat {
+	return firstPrice(quote.Ask, quote.Rate, quote.Bid)
+}
+
+func firstPrice(prices ...string) *big.Rat {
+	for _, price := range prices {
+		if value, _, err := parseDecimal(price); err == nil && value.Sign() > 0 {
+			return value
+		}
+	}
+	return new(big.Rat)
+}
+
+func newRateGraph() *RateGraph {
+	return &RateGraph{edges: make(map[string][]graphEdge)}
+}
+
+func (graph *RateGraph) addEdge(from string, to string, rate *big.Rat) {
+	if rate.Sign() > 0 {
+		graph.edges[from] = append(graph.edges[from], graphEdge{to: to, rate: rate})
+	}
+}
+
+// addMid adds both directions of a pair at a single mid price.
+func (graph *RateGraph) addMid(from string, to string, rate *big.Rat) {
+	if rate.Sign() > 0 {
+		graph.addEdge(from, to, rate)
+		graph.addEdge(to, from, new(big.Rat).Inv(rate))
+	}
+}
+
+// addQuote adds a pair at its quoted sides: selling From earns the bid,
+// and buying From with To pays the ask.
+func (graph *RateGraph) addQuote(quote PairQuote) {
+	graph.addEdge(quote.From, quote.To, quote.sell())
+	if buy := quote.buy(); buy.Sign() > 0 {
+		graph.addEdge(quote.To, quote.From, new(big.Rat).Inv(buy))
+	}
+}
+
+// route finds a path from one asset to another of at most maxRouteHops
+// pairs. RouteBest prefers the highest compounded rate and RouteShortest
+// the fewest hops, each breaking ties with the other.
+func (graph *RateGraph) route(from string, to string, mode RoutingMode) (RatePath, bool) {
+	var best RatePath
+	found := false
+
+	better := func(candidate RatePath) bool {
+		if !found {
+			return true
+		}
+		byRate := candidate.Rate.Cmp(best.Rate)
+		byHops := len(best.Assets) - len(candidate.Assets)
+		if mode == RouteShortest {
+			return byHops > 0 || (byHops == 0 && byRate > 0)
+		}
+		return byRate > 0 || (byRate == 0 && byHops > 0)
+	}
+
+	var walk func(path []string, rate *big.Rat)
+	walk = func(path []string, rate *big.Rat) {
+		current := path[len(path)-1]
+		if current == to {
+			candidate := RatePath{Assets: slices.Clone(path), Rate: rate}
+			if better(candidate) {
+				best, found = candidate, true
+			}
+			return
+		}
+		if len(path) > maxRouteHops {
+			return
+		}
+		for _, edge := range graph.edges[current] {
+			if slices.Contains(path, edge.to) {
+				continue
+			}
+			walk(append(path, edge.to), new(big.Rat).Mul(rate, edge.rate))
+		}
+	}
+	walk([]string{from}, big.NewRat(1, 1))
+
+	return best, found
+}
+
+func routingMode() RoutingMode {
+	if RoutingMode(strings.ToLower(getEnvVar("GRAPH_ROUTING"))) == RouteShortest {
+		return RouteShortest
+	}
+	return RouteBest
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+var testPairQuotes = []PairQuote{
+	{From: "ADA", To: "USDT", Bid: "0.45", Ask: "0.46"},
+	{From: "USDT", To: "EUR", Bid: "0.92", Ask: "0.93"},
+	{From: "ADA", To: "EUR", Rate: "0.40"},
+}
+
+func TestRateGraphRoute(t *testing.T) {
+	graph := newRateGraph()
+	for _, quote := range testPairQuotes {
+		graph.addQuote(quote)
+	}
+
+	cases := []struct {
+		From     string
+		To       string
+		Mode     RoutingMode
+		Expected *big.Rat
+		Path     string
+	}{
+		{"ADA", "EUR", RouteBest, big.NewRat(414, 1000), "ADA→USDT→EUR"},
+		{"ADA", "EUR", RouteShortest, big.NewRat(40, 100), "ADA→EUR"},
+		{"EUR", "ADA", RouteBest, big.NewRat(5, 2), "EUR→ADA"},
+		{"EUR", "USDT", RouteBest, big.NewRat(9, 8), "EUR→ADA→USDT"},
+		{"EUR", "USDT", RouteShortest, big.NewRat(100, 93), "EUR→USDT"},
+	}
+
+	for _, testCase := range cases {
+		path, ok := graph.route(testCase.From, testCase.To, testCase.Mode)
+		if !ok || path.Rate.Cmp(testCase.Expected) != 0 || path.String() != testCase.Path {
+			t.Errorf("FAILED: route(%s, %s, %s) Expected %s along %s, got %v along %s", testCase.From, testCase.To, testCase.Mode, testCase.Expected, testCase.Path, path.Rate, path)
+		}
+	}
+
+	if _, ok := graph.route("ADA", "BTC", RouteBest); ok {
+		t.Errorf("FAILED: Expected no route from ADA to BTC")
+	}
+}
+
+func TestConvertThroughQuotedPairs(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "pairs.json")
+	data, _ := json.Marshal(testPairQuotes)
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PAIR_QUOTES_FILE", fileName)
+
+	snapshot := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0.8})
+	snapshot.Quotes = testPairQuotes
+
+	dataInput := DataInput{Value: newMoney(1000, "ADA"), CurrencyTo: "GBP"}
+	quote, err := priceConversion(dataInput, snapshot)
+	if err != nil || quote.Net.String() != "368.00" || quote.Route != "ADA→USDT→EUR→USD→GBP" {
+		t.Errorf("FAILED: Expected 368.00 GBP via ADA→USDT→EUR→USD→GBP, got %s via %q (%v)", quote.Net, quote.Route, err)
+	}
+
+	provenance, err := snapshot.provenance(dataInput)
+	if err != nil || !slices.Equal(provenance.Path, []string{"ADA", "USDT", "EUR", "USD", "GBP"}) {
+		t.Errorf("FAILED: Unexpected provenance path %v (%v)", provenance.Path, err)
+	}
+
+	if quote, err := priceConversion(DataInput{Value: newMoney(100, "EUR"), CurrencyTo: "GBP"}, snapshot); err != nil || quote.Route != "" {
+		t.Errorf("FAILED: Expected the rate table to price EUR to GBP, got route %q (%v)", quote.Route, err)
+	}
+}
+
+func TestPairQuoteValidation(t *testing.T) {
+	invalid := []PairQuote{
+		{From: "ADA", To: "ADA", Rate: "1"},
+		{From: "ADA", To: "USDT"},
+		{From: "ADA", To: "USDT", Bid: "-1"},
+		{From: "ADA", To: "USDT", Bid: "0.47", Ask: "0.46"},
+	}
+
+	for _, quote := range invalid {
+		if err := quote.validate(); err == nil {
+			t.Errorf("FAILED: Expected %+v to be rejected", quote)
+		}
+	}
+}
@@ -3,25 +3,54 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
-	"strings"
-	"unicode"
 )
 
+// DataInput is a parsed conversion request. Target is set instead of
+// Value's amount for reverse conversions, which solve for the amount of
+// Value.Currency needed to end up with Target after fees and rounding.
+// Targets lists every currency asked for; CurrencyTo is the one currently
+// being converted to. Date is the YYYY-MM-DD day the conversion is for, or
+// empty for today. Unit and UnitTo are the weights precious metal amounts
+// are counted in, defaulting to the troy ounce they are quoted per.
 type DataInput struct {
-	Value        float64
-	CurrencyFrom string
-	CurrencyTo   string
+	Value      Money
+	Unit       string
+	CurrencyTo string
+	UnitTo     string
+	Targets    []string
+	Target     Money
+	Date       string
+	Rounding   Rounding
+	Profile    string
+	Overlay    string
 }
 
-func getInput() string {
+func (dataInput DataInput) withTarget(currency string) DataInput {
+	dataInput.CurrencyTo = currency
+	if dataInput.isReverse() {
+		dataInput.Target.Currency = currency
+	}
+	return dataInput
+}
+
+func (dataInput DataInput) isReverse() bool {
+	return dataInput.Target.Amount != nil
+}
+
+// stdinReader is shared between prompts so that lines piped in together
+// are not lost in a discarded buffer.
+var stdinReader = bufio.NewReader(os.Stdin)
 
-	reader := bufio.NewReader(os.Stdin)
+func getInput() string {
 	fmt.Print("> ")
-	rawInput, err := reader.ReadString('\n')
+	rawInput, err := stdinReader.ReadString('\n')
 
-	if err != nil {
+	if err == io.EOF && rawInput == "" {
+		fmt.Println()
+		os.Exit(0)
+	} else if err != nil && err != io.EOF {
 		fmt.Println("Error reading input:", err)
 		return getInput()
 	}
@@ -36,75 +65,3 @@ func checkForQuitSequence(input string) {
 		os.Exit(0)
 	}
 }
-
-func formatInput(input string) []string {
-	var filterAlphanumericAndDash = func(input string) string {
-		var result []rune
-
-		for _, char := range input {
-			if unicode.IsLetter(char) || unicode.IsDigit(char) || unicode.IsSpace(char) || char == '-' {
-				result = append(result, char)
-			}
-		}
-
-		return string(result)
-	}
-
-	var separateIntegerFromNumber = func(input string) []string {
-		var i int
-
-		for i < len(input) && unicode.IsDigit(rune(input[i])) {
-			i++
-		}
-
-		if i == 0 || i == len(input) {
-			return []string{input}
-		}
-
-		return []string{input[:i], input[i:]}
-	}
-
-	filteredInput := filterAlphanumericAndDash(input)
-	capitalizedInput := strings.ToUpper(filteredInput)
-	splitInput := strings.Fields(capitalizedInput)
-
-	var formattedInput []string
-	for _, word := range splitInput {
-		slice := separateIntegerFromNumber(word)
-		formattedInput = append(formattedInput, slice...)
-	}
-
-	return formattedInput
-}
-
-func inputWrapper(rates map[string]float64) DataInput {
-	var input DataInput
-	rawInput := getInput()
-	formattedInput := formatInput(rawInput)
-
-	for i := 0; i < len(formattedInput); i++ {
-		if input.Value == 0 {
-			val, err := strconv.ParseFloat(formattedInput[i], 64)
-			if err == nil {
-				input.Value = val
-			}
-		}
-		if checkValidCurrency(rates, formattedInput[i]) {
-			currentWord := formattedInput[i]
-			if input.CurrencyFrom == "" {
-				input.CurrencyFrom = currentWord
-			} else if input.CurrencyTo == "" {
-				input.CurrencyTo = currentWord
-			}
-		}
-	}
-
-	if input.Value == 0 {
-		input.Value = 1
-	}
-
-	if input.CurrencyFrom == "" || input.CurrencyTo == "" {
-		return inputWrapper(rates)
-	}
-	return input
-}
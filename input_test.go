@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math/big"
 	"testing"
 )
 
@@ -10,9 +11,6 @@ type TestData struct {
 }
 
 func TestEvaluateInput(t *testing.T) {
-	cacheData := readFromCache()
-	rates := castRateFromLatest(cacheData)
-
 	allTestData := []TestData{
 		{
 			Inputs: []string{
@@ -25,7 +23,7 @@ func TestEvaluateInput(t *testing.T) {
 				"How much is 1 usd in EUR", "How MUHC is 1 usd in EUR", "How MUCH is 1 usd in EUR",
 				"usd to eur", "usd in eur", "USD in EUR",
 			},
-			Output: DataInput{Value: 1, CurrencyFrom: "USD", CurrencyTo: "EUR"},
+			Output: DataInput{Value: Money{Amount: big.NewRat(1, 1), Currency: "USD"}, CurrencyTo: "EUR"},
 		},
 		{
 			Inputs: []string{
@@ -38,7 +36,7 @@ func TestEvaluateInput(t *testing.T) {
 				"How much is 100 jpy in USD", "How MUHC is 100 jpy in USD", "How MUCH is 100 jpy in USD",
 				"100jpy to usd", "100jpy in usd", "100JPY in USD",
 			},
-			Output: DataInput{Value: 100, CurrencyFrom: "JPY", CurrencyTo: "USD"},
+			Output: DataInput{Value: Money{Amount: big.NewRat(100, 1), Currency: "JPY"}, CurrencyTo: "USD"},
 		},
 		{
 			Inputs: []string{
@@ -51,7 +49,7 @@ func TestEvaluateInput(t *testing.T) {
 				"How much is 1 cad in AUD", "How MUHC is 1 cad in AUD", "How MUCH is 1 cad in AUD",
 				"cad to aud", "cad in aud", "CAD in AUD",
 			},
-			Output: DataInput{Value: 1, CurrencyFrom: "CAD", CurrencyTo: "AUD"},
+			Output: DataInput{Value: Money{Amount: big.NewRat(1, 1), Currency: "CAD"}, CurrencyTo: "AUD"},
 		},
 		{
 			Inputs: []string{
@@ -64,18 +62,25 @@ func TestEvaluateInput(t *testing.T) {
 				"How much is 50.5 eur in CHF", "How MUHC is 50.5 eur in CHF", "How MUCH is 50.5 eur in CHF",
 				"50.5eur to chf", "50.5eur in chf", "50.5EUR in CHF",
 			},
-			Output: DataInput{Value: 50.5, CurrencyFrom: "EUR", CurrencyTo: "CHF"},
+			Output: DataInput{Value: Money{Amount: big.NewRat(101, 2), Currency: "EUR"}, CurrencyTo: "CHF"},
 		},
 	}
 
 	for _, testData := range allTestData {
 		for _, input := range testData.Inputs {
-			evalOutput := inputWrapper(rates)
-			if testData.Output != evalOutput {
-				t.Errorf(`FAILED: evaluateInput(rates, %v) Expected %v, got %v.`, input, testData.Output, evalOutput)
+			query, err := parseQuery(input)
+			evalOutput := query.dataInput()
+			if err != nil || !sameDataInput(testData.Output, evalOutput) {
+				t.Errorf(`FAILED: parseQuery(%v) Expected %v, got %v (%v).`, input, testData.Output, evalOutput, err)
 			} else {
-				t.Logf(`PASSED: evaluateInput(rates, %v) Returned %v`, input, testData.Output)
+				t.Logf(`PASSED: parseQuery(%v) Returned %v`, input, testData.Output)
 			}
 		}
 	}
 }
+
+func sameDataInput(expected DataInput, actual DataInput) bool {
+	return expected.Value.Currency == actual.Value.Currency &&
+		expected.CurrencyTo == actual.CurrencyTo &&
+		expected.Value.amount().Cmp(actual.Value.amount()) == 0
+}
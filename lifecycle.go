@@ -0,0 +1,89 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+)
+
+//go:embed lifecycle.json
+var lifecycleData []byte
+
+// LifecycleEvent records a currency being replaced by its successor on Date,
+// with one unit of Successor worth Factor units of Code. Kind is "euro" for
+// the irrevocable euro conversion rates, "redenomination" when a country
+// dropped zeros, or "replacement" for a new currency at a fixed ratio.
+type LifecycleEvent struct {
+	Code      string `json:"code"`
+	Successor string `json:"successor"`
+	Date      string `json:"date"`
+	Factor    string `json:"factor"`
+	Kind      string `json:"kind"`
+}
+
+var lifecycleEvents = loadLifecycleEvents(lifecycleData)
+
+func loadLifecycleEvents(data []byte) []LifecycleEvent {
+	var events []LifecycleEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		log.Fatalf("Failed to parse currency lifecycle table: %v", err)
+	}
+	return events
+}
+
+func (event LifecycleEvent) factor() *big.Rat {
+	factor, _, err := parseDecimal(event.Factor)
+	if err != nil {
+		log.Fatalf("Invalid factor %q for %s in currency lifecycle table", event.Factor, event.Code)
+	}
+	return factor
+}
+
+// legacyRoute finds the currency amounts in code are priced through on date,
+// along with how many units of code one unit of it is worth. Withdrawn
+// currencies follow their successors forward; a currency queried before it
+// was introduced is priced through the single currency it replaced.
+func legacyRoute(code string, date string) (string, *big.Rat, error) {
+	if date == "" {
+		date = time.Now().UTC().Format("2006-01-02")
+	}
+	factor := big.NewRat(1, 1)
+
+	for moved := true; moved; {
+		moved = false
+		for _, event := range lifecycleEvents {
+			if event.Code == code && date >= event.Date {
+				factor.Mul(factor, event.factor())
+				code = event.Successor
+				moved = true
+				break
+			}
+		}
+	}
+
+	currency, _ := lookupCurrency(code)
+	for currency.Introduced != "" && date < currency.Introduced {
+		var predecessors []LifecycleEvent
+		for _, event := range lifecycleEvents {
+			if event.Successor == code && event.Date == currency.Introduced {
+				predecessors = append(predecessors, event)
+			}
+		}
+		if len(predecessors) != 1 {
+			return code, factor, &ConversionError{Kind: ErrNotInCirculation, Currency: code, Detail: fmt.Sprintf("introduced %s", currency.Introduced)}
+		}
+
+		factor.Quo(factor, predecessors[0].factor())
+		code = predecessors[0].Code
+		currency, _ = lookupCurrency(code)
+	}
+
+	if currency.Withdrawn != "" && date >= currency.Withdrawn {
+		return code, factor, &ConversionError{Kind: ErrNotInCirculation, Currency: code, Detail: fmt.Sprintf("withdrawn %s", currency.Withdrawn)}
+	}
+
+	return code, factor, nil
+}
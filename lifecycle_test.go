@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestLegacyRoute(t *testing.T) {
+	cases := []struct {
+		Code     string
+		Date     string
+		Routed   string
+		Factor   string
+		Expected error
+	}{
+		{"DEM", "2001-05-01", "EUR", "1.95583", nil},
+		{"HRK", "2022-12-31", "HRK", "1", nil},
+		{"HRK", "2023-01-01", "EUR", "7.5345", nil},
+		{"VEB", "2020-01-01", "VES", "100000000", nil},
+		{"VES", "2015-01-01", "VEF", "0.00001", nil},
+		{"ZWL", "2024-06-01", "ZWG", "2498.7242", nil},
+		// ZWD became ZWN, then ZWR, then ZWL, dropping 3, 10 and 12 zeros.
+		{"ZWD", "2007-01-01", "ZWN", "1000", nil},
+		{"ZWN", "2008-09-01", "ZWR", "10000000000", nil},
+		{"ZWD", "2010-01-01", "ZWL", "10000000000000000000000000", nil},
+		{"ZWL", "2008-09-01", "ZWR", "0.000000000001", nil},
+		{"ZWL", "2005-01-01", "ZWD", "0.0000000000000000000000001", nil},
+		{"USD", "2001-05-01", "USD", "1", nil},
+		{"EUR", "1998-12-31", "EUR", "", ErrNotInCirculation},
+	}
+
+	for _, testCase := range cases {
+		routed, factor, err := legacyRoute(testCase.Code, testCase.Date)
+		if testCase.Expected != nil {
+			if !errors.Is(err, testCase.Expected) {
+				t.Errorf("FAILED: legacyRoute(%s, %s) Expected %v, got %v", testCase.Code, testCase.Date, testCase.Expected, err)
+			}
+			continue
+		}
+
+		expected, _, _ := parseDecimal(testCase.Factor)
+		if err != nil || routed != testCase.Routed || factor.Cmp(expected) != 0 {
+			t.Errorf("FAILED: legacyRoute(%s, %s) Expected %s at %s, got %s at %s (%v)", testCase.Code, testCase.Date, testCase.Routed, testCase.Factor, routed, formatRate(factor), err)
+		}
+	}
+}
+
+func TestConvertLegacyCurrencies(t *testing.T) {
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9, "HRK": 6.8})
+
+	cases := []struct {
+		Input    DataInput
+		Expected string
+	}{
+		{DataInput{Value: newMoney(100, "DEM"), CurrencyTo: "FRF", Date: "2001-05-01"}, "335.39"},
+		{DataInput{Value: newMoney(100, "DEM"), CurrencyTo: "USD", Date: "2001-05-01"}, "56.81"},
+		{DataInput{Value: newMoney(75345, "HRK"), CurrencyTo: "EUR", Date: "2024-01-01"}, "10000.00"},
+		{DataInput{Value: newMoney(68, "HRK"), CurrencyTo: "USD", Date: "2020-01-01"}, "10.00"},
+	}
+
+	for _, testCase := range cases {
+		result, err := convert(testCase.Input, rates)
+		if err != nil || result.String() != testCase.Expected {
+			t.Errorf("FAILED: convert(%v) Expected %s, got %s (%v)", testCase.Input, testCase.Expected, result, err)
+		}
+	}
+
+	input := DataInput{Value: Money{Amount: big.NewRat(1, 1), Currency: "EUR"}, CurrencyTo: "USD", Date: "1998-06-01"}
+	if _, err := convert(input, rates); !errors.Is(err, ErrNotInCirculation) {
+		t.Errorf("FAILED: Expected EUR to be unavailable in 1998, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// Locale is the language and region a user writes in, as in "en-CA". The
+// region settles symbols and names shared by several currencies, so "$" is
+// the Canadian dollar in Canada.
+type Locale struct {
+	Language string
+	Region   string
+}
+
+// parseLocale reads a locale written as "en-CA", "en_CA" or "en_CA.UTF-8".
+// Anything it does not recognise leaves the field empty.
+func parseLocale(text string) Locale {
+	text, _, _ = strings.Cut(text, ".")
+	language, region, _ := strings.Cut(strings.ReplaceAll(text, "_", "-"), "-")
+	return Locale{Language: strings.ToLower(language), Region: strings.ToUpper(region)}
+}
+
+// Languages by the mark they write decimals with. Swiss usage, which puts
+// a point before decimals whatever the language, is handled by region.
+var (
+	decimalPointLanguages = []string{"en", "ja", "zh", "ko", "hi", "th", "he", "ms", "ga"}
+	decimalCommaLanguages = []string{"de", "fr", "es", "it", "pt", "nl", "ru", "pl", "sv", "nb", "nn", "no", "da", "fi", "cs", "sk", "tr", "el", "hu", "ro", "uk", "id", "vi"}
+)
+
+// userLocale is the locale set by LOCALE, which may be empty.
+func userLocale() Locale {
+	return parseLocale(getEnvVar("LOCALE"))
+}
+
+// currency is the currency of the locale's region, if it is known.
+func (locale Locale) currency() string {
+	return regionCurrencies[locale.Region]
+}
+
+// decimalMark is the mark the locale writes before decimals, or zero when
+// it is not known.
+func (locale Locale) decimalMark() rune {
+	switch {
+	case locale.Region == "CH" || locale.Region == "LI":
+		return '.'
+	case slices.Contains(decimalPointLanguages, locale.Language):
+		return '.'
+	case slices.Contains(decimalCommaLanguages, locale.Language):
+		return ','
+	}
+	return 0
+}
+
+func (locale Locale) String() string {
+	if locale.Region == "" {
+		return locale.Language
+	}
+	return locale.Language + "-" + locale.Region
+}
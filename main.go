@@ -1,10 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"regexp"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 )
@@ -34,6 +34,13 @@ func runCLI() {
 		fmt.Println("Conversion is forced to update from API")
 	}
 
+	if mode := getFlagValue("rounding"); mode != "" {
+		if _, err := parseRoundingMode(mode); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+
 	for {
 		rawInput := getInput()
 
@@ -42,11 +49,43 @@ func runCLI() {
 			os.Exit(0)
 		}
 
-		input, date := extractDate(rawInput)
-		formattedInput := formatInput(input)
+		query, err := clarifyQuery(rawInput)
+		if err != nil {
+			displayParseError(rawInput, err)
+			continue
+		}
+		date := query.Date
+
+		if len(query.Interpretations) > 0 {
+			displayInterpretation(query)
+		}
 
-		var rates map[string]float64
-		var err error
+		inputData := query.dataInput()
+
+		// Settings named in the query win over the command line flags.
+		if inputData.Profile == "" {
+			inputData.Profile = getFlagValue("profile")
+		}
+		if inputData.Overlay == "" {
+			inputData.Overlay = getFlagValue("overlay")
+		}
+		if inputData.Rounding.Mode == "" {
+			inputData.Rounding.Mode = RoundingMode(getFlagValue("rounding"))
+		}
+
+		if !query.Period.isZero() {
+			results, err := rateSeries(inputData, query.Period, getHistoricalRate)
+			if err != nil {
+				fmt.Println("Error:", err)
+				continue
+			}
+			for _, series := range results {
+				displaySeries(series)
+			}
+			continue
+		}
+
+		var snapshot RateSnapshot
 
 		if date != "" {
 			historicalData, err := getHistoricalRate(date)
@@ -54,30 +93,204 @@ func runCLI() {
 				fmt.Println("Error:", err)
 				continue
 			}
-			rates = castRateFromLatest(historicalData)
+			snapshot = historicalData
 		} else {
 			now := time.Now().Unix()
-			rates, err = caller(now)
+			snapshot, err = caller(now)
 			if err != nil {
 				fmt.Println("Error: No currency exchange data found")
 				continue
 			}
 		}
 
-		inputData := processInput(formattedInput, rates)
+		// Every amount, target and step of a chain is converted against
+		// the same rates, so the answers stay consistent with each other.
+		totals := runBatch(listInputs(inputData, query.Sources), query.Chain, func(step DataInput) (Money, error) {
+			return displayConversion(step, snapshot, date)
+		}, func(step DataInput, err error) {
+			fmt.Println("Error:", err)
+		})
+		if query.Total {
+			for _, total := range totals {
+				fmt.Printf("Total: %s %s\n", displayMoney(total.Value), quantityLabel(total.Value.Currency, total.Unit))
+			}
+		}
+	}
+}
 
-		if inputData.Value == 0 && inputData.CurrencyFrom == "" && inputData.CurrencyTo == "" {
-			fmt.Println("Invalid input. Please try again.")
-			continue
+// displayConversion prints a single conversion and returns the amount
+// received after any fees, or the amount needed for a target amount.
+func displayConversion(inputData DataInput, snapshot RateSnapshot, date string) (Money, error) {
+	rounding, err := resolveRounding(inputData.CurrencyTo, string(inputData.Rounding.Mode))
+	if err != nil {
+		return Money{}, err
+	}
+	inputData.Rounding = rounding
+
+	if inputData.isReverse() {
+		solved, quote, err := reversePriceConversion(inputData, snapshot)
+		if err != nil {
+			return Money{}, err
+		}
+		var notes string
+		if quote.Overlay != "" {
+			notes = fmt.Sprintf(" (overlay: %s)", quote.Overlay)
+		}
+		if quote.Route != "" {
+			notes += fmt.Sprintf(" (via %s)", quote.Route)
+		}
+		fmt.Printf("To receive %s %s you need %s %s%s\n", displayMoney(inputData.Target), quantityLabel(inputData.Target.Currency, inputData.UnitTo), displayMoney(solved.Value), quantityLabel(solved.Value.Currency, solved.Unit), notes)
+		if quote.Fees.Sign() != 0 || quote.EffectiveRate.Cmp(quote.MidRate) != 0 {
+			displayQuote(quote)
+		}
+		if checkForVerbose() {
+			provenance, err := snapshot.provenance(solved)
+			if err != nil {
+				return Money{}, err
+			}
+			displayProvenance(provenance)
 		}
+		return solved.Value, nil
+	}
 
-		result := convert(inputData, rates)
-		if date != "" {
-			fmt.Printf("On %s: %.2f %s = %.2f %s\n", date, inputData.Value, inputData.CurrencyFrom, result, inputData.CurrencyTo)
-		} else {
-			fmt.Printf("%.2f %s = %.2f %s\n", inputData.Value, inputData.CurrencyFrom, result, inputData.CurrencyTo)
+	result, err := convert(inputData, snapshot)
+	if err != nil {
+		return Money{}, err
+	}
+
+	quote, err := priceConversion(inputData, snapshot)
+	if err != nil {
+		return Money{}, err
+	}
+
+	var notes string
+	if rounding.Mode != RoundHalfUp {
+		notes = fmt.Sprintf(" (rounding: %s)", rounding)
+	}
+	if quote.Overlay != "" {
+		notes += fmt.Sprintf(" (overlay: %s)", quote.Overlay)
+	}
+	if quote.Route != "" {
+		notes += fmt.Sprintf(" (via %s)", quote.Route)
+	}
+	if date != "" {
+		fmt.Printf("On %s: %s %s = %s %s%s\n", date, displayMoney(inputData.Value), quantityLabel(inputData.Value.Currency, inputData.Unit), displayMoney(result), quantityLabel(result.Currency, inputData.UnitTo), notes)
+	} else {
+		fmt.Printf("%s %s = %s %s%s\n", displayMoney(inputData.Value), quantityLabel(inputData.Value.Currency, inputData.Unit), displayMoney(result), quantityLabel(result.Currency, inputData.UnitTo), notes)
+	}
+	if quote.Fees.Sign() != 0 || quote.EffectiveRate.Cmp(quote.MidRate) != 0 {
+		displayQuote(quote)
+	}
+	if checkForVerbose() {
+		provenance, err := snapshot.provenance(inputData)
+		if err != nil {
+			return Money{}, err
 		}
+		displayProvenance(provenance)
 	}
+	return quote.Net, nil
+}
+
+func displayQuote(quote Quote) {
+	fmt.Printf("  Mid rate:       %s\n", formatRate(quote.MidRate))
+	fmt.Printf("  Effective rate: %s\n", formatRate(quote.EffectiveRate))
+	fmt.Printf("  Fees:           %s %s\n", displayMoney(quote.Fees), quote.Fees.Currency)
+	fmt.Printf("  You receive:    %s %s\n", displayMoney(quote.Net), quote.Net.Currency)
+}
+
+// displaySeries prints a rate series day by day, followed by how the rate
+// moved over the range.
+func displaySeries(series RateSeries) {
+	fmt.Printf("%s %s to %s from %s to %s\n", series.Amount, series.From, series.To, series.Start, series.End)
+	for _, point := range series.Points {
+		fmt.Printf("  %s  %s  %s %s\n", point.Date, point.Rate, displayMoney(point.Value), point.Value.Currency)
+	}
+	if len(series.Missing) > 0 {
+		fmt.Printf("  No rates for %s\n", strings.Join(series.Missing, ", "))
+	}
+
+	summary := series.Summary
+	fmt.Printf("  Change:         %s → %s (%s, %s%%)\n", summary.StartRate, summary.EndRate, summary.Change, summary.ChangePercent)
+	fmt.Printf("  Min:            %s on %s\n", summary.Min, summary.MinDate)
+	fmt.Printf("  Max:            %s on %s\n", summary.Max, summary.MaxDate)
+	fmt.Printf("  Average:        %s\n", summary.Average)
+}
+
+// displayInterpretation shows how symbols, names and slang in the query
+// were read, so a misunderstanding is caught before the answer is trusted.
+func displayInterpretation(query Query) {
+	notes := make([]string, len(query.Interpretations))
+	for i, interpretation := range query.Interpretations {
+		notes[i] = interpretation.String()
+	}
+	fmt.Printf("Interpreted as %s (%s)\n", query, strings.Join(notes, ", "))
+}
+
+// clarifyQuery parses a query, asking the user about each word it cannot
+// settle on its own until the query is unambiguous.
+func clarifyQuery(rawInput string) (Query, error) {
+	options := defaultParseOptions()
+	options.Resolutions = make(map[int]string)
+	for {
+		query, err := parseQueryWith(rawInput, options)
+		var ambiguity *AmbiguityError
+		if !errors.As(err, &ambiguity) {
+			return query, err
+		}
+		options.Resolutions[ambiguity.Pos] = askClarification(ambiguity)
+	}
+}
+
+// askClarification puts an AmbiguityError's question to the user and
+// returns the currency chosen, or an empty string when the word is not a
+// currency.
+func askClarification(ambiguity *AmbiguityError) string {
+	if ambiguity.Optional && len(ambiguity.Candidates) == 1 {
+		fmt.Println(ambiguity.Question(), "[y/n]")
+		answer := strings.ToLower(strings.TrimSpace(getInput()))
+		if answer == "y" || answer == "yes" {
+			return ambiguity.Candidates[0]
+		}
+		return ""
+	}
+
+	for {
+		fmt.Println(ambiguity.Question())
+		answer := strings.ToUpper(strings.TrimSpace(getInput()))
+		if slices.Contains(ambiguity.Candidates, answer) {
+			return answer
+		}
+		if ambiguity.Optional && answer == "" {
+			return ""
+		}
+	}
+}
+
+// displayParseError shows where in the query parsing failed.
+func displayParseError(input string, err error) {
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("  " + strings.TrimRight(input, "\r\n"))
+	fmt.Println("  " + strings.Repeat(" ", parseErr.Pos) + "^")
+	fmt.Println("Invalid input:", parseErr)
+}
+
+func displayProvenance(provenance Provenance) {
+	var staleNote string
+	if provenance.Stale {
+		staleNote = " (stale)"
+	}
+
+	fmt.Printf("  Rate:           %s (inverse %s)\n", provenance.Rate, provenance.InverseRate)
+	if provenance.MidRate != "" && provenance.MidRate != provenance.Rate {
+		fmt.Printf("  Market mid:     %s\n", provenance.MidRate)
+	}
+	fmt.Printf("  Rates as of:    %s%s\n", provenance.RatesTimestamp.Format(time.RFC3339), staleNote)
+	fmt.Printf("  Source:         %s, base %s, via %s\n", provenance.Provider, provenance.Base, provenance.CacheLayer)
+	fmt.Printf("  Path:           %s\n", strings.Join(provenance.Path, " → "))
 }
 
 func displayWelcomeScreen() {
@@ -88,57 +301,26 @@ func displayWelcomeScreen() {
 	fmt.Println("  '5 USD to EUR'")
 	fmt.Println("  'How much is 100 JPY in GBP?'")
 	fmt.Println("  '10 USD to EUR on 2022-01-01'")
+	fmt.Println("  'GBP/JPY over the last 30 days'")
+	fmt.Println("  'How much USD for 500 EUR?'")
+	fmt.Println("  '100 USD to EUR, GBP and JPY'")
+	fmt.Println("  '10 EUR, 20 GBP and 3000 JPY to USD in total'")
+	fmt.Println("  '100 USD to EUR then to CHF'")
+	fmt.Println("  '250 g gold in EUR'")
+	fmt.Println("  '1000 ADA to EUR'")
+	fmt.Println("  '$100 to €' or '20 quid in Japanese yen'")
+	fmt.Println("  '500 EURUSD to GBP'")
 	fmt.Println()
 	fmt.Println("To exit, type 'exit', 'quit', 'end', or 'thank you'")
 	fmt.Println("====================================")
 	fmt.Println()
 }
 
+// shouldTerminate reports whether the input is a termination phrase on its
+// own, so that queries such as "at the end of last quarter" still run.
 func shouldTerminate(input string) bool {
 	terminationPhrases := []string{"exit", "quit", "end", "thank you", "goodbye", "bye", "finished", "done"}
-	lowercaseInput := strings.ToLower(strings.TrimSpace(input))
-
-	for _, phrase := range terminationPhrases {
-		if strings.Contains(lowercaseInput, phrase) {
-			return true
-		}
-	}
-
-	return false
-}
-
-func extractDate(input string) (string, string) {
-	re := regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
-	date := re.FindString(input)
-	if date != "" {
-		return strings.TrimSpace(strings.Replace(input, date, "", -1)), date
-	}
-	return input, ""
-}
-
-func processInput(formattedInput []string, rates map[string]float64) DataInput {
-	var input DataInput
-
-	for i := 0; i < len(formattedInput); i++ {
-		if input.Value == 0 {
-			val, err := strconv.ParseFloat(formattedInput[i], 64)
-			if err == nil {
-				input.Value = val
-			}
-		}
-		if checkValidCurrency(rates, formattedInput[i]) {
-			currentWord := formattedInput[i]
-			if input.CurrencyFrom == "" {
-				input.CurrencyFrom = currentWord
-			} else if input.CurrencyTo == "" {
-				input.CurrencyTo = currentWord
-			}
-		}
-	}
-
-	if input.Value == 0 {
-		input.Value = 1
-	}
+	lowercaseInput := strings.ToLower(strings.Trim(input, " \t\r\n.!"))
 
-	return input
+	return slices.Contains(terminationPhrases, lowercaseInput)
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"slices"
 	"testing"
 )
 
@@ -9,3 +10,52 @@ func TestMain(m *testing.M) {
 	exitCode := m.Run()
 	os.Exit(exitCode)
 }
+
+func TestProcessInputReverse(t *testing.T) {
+	for _, input := range []string{"how much USD for 500 EUR", "How many usd do I need to get 500 EUR?"} {
+		dataInput := parseTestQuery(t, input)
+
+		if !dataInput.isReverse() || dataInput.Value.Currency != "USD" || dataInput.Target.String() != "500" || dataInput.Target.Currency != "EUR" {
+			t.Errorf("FAILED: parseQuery(%q) Expected USD for 500 EUR, got %+v", input, dataInput)
+		}
+	}
+
+	if dataInput := parseTestQuery(t, "500 EUR for USD"); dataInput.isReverse() {
+		t.Errorf("FAILED: parseQuery(%q) Expected a forward conversion", "500 EUR for USD")
+	}
+}
+
+func TestProcessInputMultipleTargets(t *testing.T) {
+	dataInput := parseTestQuery(t, "100 USD to EUR, GBP and JPY")
+
+	if dataInput.Value.Currency != "USD" || dataInput.CurrencyTo != "EUR" || !slices.Equal(dataInput.Targets, []string{"EUR", "GBP", "JPY"}) {
+		t.Errorf("FAILED: parseQuery Expected USD to EUR, GBP and JPY, got %+v", dataInput)
+	}
+
+	// An unknown code among the targets is kept so it can fail on its own.
+	dataInput = parseTestQuery(t, "100 USD to EUR, XYZ and GBP please")
+	if !slices.Equal(dataInput.Targets, []string{"EUR", "XYZ", "GBP"}) {
+		t.Errorf("FAILED: parseQuery Expected targets EUR, XYZ and GBP, got %v", dataInput.Targets)
+	}
+
+	dataInput = parseTestQuery(t, "100 USD to XYZ, EUR")
+	if !slices.Equal(dataInput.Targets, []string{"XYZ", "EUR"}) {
+		t.Errorf("FAILED: parseQuery Expected targets XYZ and EUR, got %v", dataInput.Targets)
+	}
+}
+
+func TestShouldTerminate(t *testing.T) {
+	cases := map[string]bool{
+		"exit\n":       true,
+		"Thank you!\n": true,
+		"bye":          true,
+		"100 USD to EUR at the end of last quarter\n": false,
+		"how much should I spend in EUR\n":            false,
+	}
+
+	for input, expected := range cases {
+		if shouldTerminate(input) != expected {
+			t.Errorf("FAILED: shouldTerminate(%q) should be %v", input, expected)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+)
+
+// gramsPerTroyOunce is exact by definition of the troy ounce.
+var gramsPerTroyOunce = big.NewRat(311034768, 10000000)
+
+// metalPrecision is the number of decimals metal quantities are rounded
+// and displayed to. ISO 4217 gives metals no minor units, and at 2000 USD
+// an ounce of gold a thousandth is already worth 2 USD.
+const metalPrecision = 6
+
+// massUnitGrams gives the weight of each unit metal quantities can be
+// written in. Rates for precious metals are quoted per troy ounce.
+var massUnitGrams = map[string]*big.Rat{
+	"g":   big.NewRat(1, 1),
+	"kg":  big.NewRat(1000, 1),
+	"ozt": gramsPerTroyOunce,
+	// A tola is 180 grains, as still used for gold in South Asia and the Gulf.
+	"tola": big.NewRat(116638038, 10000000),
+}
+
+var massUnitAliases = map[string]string{
+	"G": "g", "GR": "g", "GRAM": "g", "GRAMS": "g", "GRAMME": "g", "GRAMMES": "g",
+	"KG": "kg", "KGS": "kg", "KILO": "kg", "KILOS": "kg", "KILOGRAM": "kg", "KILOGRAMS": "kg",
+	"OZ": "ozt", "OZT": "ozt", "OUNCE": "ozt", "OUNCES": "ozt", "TROY": "ozt",
+	"TOLA": "tola", "TOLAS": "tola",
+}
+
+var metalNames = map[string]string{
+	"GOLD":      "XAU",
+	"SILVER":    "XAG",
+	"PLATINUM":  "XPT",
+	"PALLADIUM": "XPD",
+}
+
+func isPreciousMetal(code string) bool {
+	for _, metal := range metalNames {
+		if code == metal {
+			return true
+		}
+	}
+	return false
+}
+
+func parseMassUnit(word string) (string, bool) {
+	unit, ok := massUnitAliases[strings.ToUpper(word)]
+	return unit, ok
+}
+
+// troyOunces is how many troy ounces one unit weighs. No unit means the
+// quantity is already in troy ounces, or is not a metal at all.
+func troyOunces(unit string) *big.Rat {
+	grams, ok := massUnitGrams[unit]
+	if !ok {
+		return big.NewRat(1, 1)
+	}
+	return new(big.Rat).Quo(grams, gramsPerTroyOunce)
+}
+
+// quantityLabel names what an amount counts, such as "g XAU" for grams of
+// gold or plain "EUR" for money.
+func quantityLabel(currency string, unit string) string {
+	if unit == "" {
+		return currency
+	}
+	return unit + " " + currency
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestProcessInputMetalQuantities(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Unit     string
+		Currency string
+		Target   string
+	}{
+		{"250 g gold in EUR", "g", "XAU", "EUR"},
+		{"1 kg XAG to USD", "kg", "XAG", "USD"},
+		{"2 tolas of gold in INR", "tola", "XAU", "INR"},
+		{"5 USD to EUR", "", "USD", "EUR"},
+	}
+
+	for _, testCase := range cases {
+		dataInput := parseTestQuery(t, testCase.Input)
+		if dataInput.Unit != testCase.Unit || dataInput.Value.Currency != testCase.Currency || dataInput.CurrencyTo != testCase.Target {
+			t.Errorf("FAILED: parseQuery(%q) Expected %s %s to %s, got %+v", testCase.Input, testCase.Unit, testCase.Currency, testCase.Target, dataInput)
+		}
+	}
+}
+
+func TestConvertMetalQuantities(t *testing.T) {
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9, "XAU": 0.0005, "XAG": 0.04})
+
+	cases := []struct {
+		Input    DataInput
+		Expected string
+	}{
+		{DataInput{Value: newMoney(250, "XAU"), Unit: "g", CurrencyTo: "EUR"}, "14467.84"},
+		{DataInput{Value: newMoney(1, "XAG"), Unit: "kg", CurrencyTo: "USD"}, "803.77"},
+		{DataInput{Value: newMoney(1, "XAU"), Unit: "tola", CurrencyTo: "USD"}, "750.00"},
+		{DataInput{Value: newMoney(1, "XAU"), CurrencyTo: "USD"}, "2000.00"},
+		{DataInput{Value: newMoney(2000, "USD"), CurrencyTo: "XAU", UnitTo: "g", Rounding: Rounding{Mode: RoundHalfUp, Increment: big.NewRat(1, 10000)}}, "31.1035"},
+	}
+
+	for _, testCase := range cases {
+		result, err := convert(testCase.Input, rates)
+		if err != nil || result.String() != testCase.Expected {
+			t.Errorf("FAILED: convert(%v) Expected %s, got %s (%v)", testCase.Input, testCase.Expected, result, err)
+		}
+	}
+
+	// A small amount of money buys a fraction of an ounce, which is kept to
+	// six places rather than PRECISION.
+	small, err := convert(DataInput{Value: newMoney(100, "USD"), CurrencyTo: "XAU"}, newRateSnapshot("USD", map[string]float64{"USD": 1, "XAU": 0.000519}))
+	if err != nil || small.String() != "0.051900" {
+		t.Errorf("FAILED: convert of 100 USD to XAU Expected 0.051900, got %s (%v)", small, err)
+	}
+
+	_, err = convert(DataInput{Value: newMoney(5, "USD"), Unit: "g", CurrencyTo: "EUR"}, rates)
+	if !errors.Is(err, ErrInvalidUnit) {
+		t.Errorf("FAILED: convert of grams of USD Expected %v, got %v", ErrInvalidUnit, err)
+	}
+}
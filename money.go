@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxDisplayPlaces bounds how many digits are printed for amounts that have
+// no terminating decimal expansion, such as the result of dividing by three.
+const maxDisplayPlaces = 18
+
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)$`)
+
+// Money is an exact decimal amount in a currency. Scale is the number of
+// fractional digits the amount is written with, so "50.50" keeps its
+// trailing zero when printed back.
+type Money struct {
+	Amount   *big.Rat
+	Scale    int
+	Currency string
+}
+
+func parseDecimal(input string) (*big.Rat, int, error) {
+	input = strings.TrimSpace(input)
+	if !decimalPattern.MatchString(input) {
+		return nil, 0, fmt.Errorf("invalid decimal amount: %q", input)
+	}
+
+	amount, ok := new(big.Rat).SetString(input)
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid decimal amount: %q", input)
+	}
+
+	scale := 0
+	if point := strings.IndexByte(input, '.'); point >= 0 {
+		scale = len(input) - point - 1
+	}
+
+	return amount, scale, nil
+}
+
+func parseMoney(amount string, currency string) (Money, error) {
+	value, scale, err := parseDecimal(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: value, Scale: scale, Currency: currency}, nil
+}
+
+func newMoney(amount int64, currency string) Money {
+	return Money{Amount: big.NewRat(amount, 1), Currency: currency}
+}
+
+// rateToRat converts a rate decoded from JSON into the exact decimal it was
+// written as upstream, rather than the binary fraction float64 stores.
+func rateToRat(rate float64) *big.Rat {
+	exact, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'g', -1, 64))
+	if !ok {
+		return new(big.Rat)
+	}
+	return exact
+}
+
+func (m Money) amount() *big.Rat {
+	if m.Amount == nil {
+		return new(big.Rat)
+	}
+	return m.Amount
+}
+
+func (m Money) Sign() int {
+	return m.amount().Sign()
+}
+
+// Round rounds half away from zero to the given number of fractional digits.
+func (m Money) Round(places int) Money {
+	increment := new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil))
+	return m.RoundWith(Rounding{Mode: RoundHalfUp, Increment: increment})
+}
+
+func (m Money) RoundWith(rounding Rounding) Money {
+	return Money{Amount: roundToIncrement(m.amount(), rounding.Increment, rounding.Mode), Scale: rounding.places(), Currency: m.Currency}
+}
+
+// decimalPlaces returns how many fractional digits value needs to be written
+// exactly, capped at maxDisplayPlaces for non-terminating expansions.
+func decimalPlaces(value *big.Rat) int {
+	denominator := new(big.Int).Set(value.Denom())
+	ten := big.NewInt(10)
+
+	for places := 0; places < maxDisplayPlaces; places++ {
+		if new(big.Int).Mod(new(big.Int).Exp(ten, big.NewInt(int64(places)), nil), denominator).Sign() == 0 {
+			return places
+		}
+	}
+	return maxDisplayPlaces
+}
+
+func (m Money) String() string {
+	return m.amount().FloatString(max(m.Scale, decimalPlaces(m.amount())))
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.String(), m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	parsed, err := parseMoney(raw.Amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
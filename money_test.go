@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	valid := map[string]string{"100": "100", "50.50": "50.50", ".5": "0.5", "-2.125": "-2.125"}
+	for input, expected := range valid {
+		money, err := parseMoney(input, "USD")
+		if err != nil || money.String() != expected {
+			t.Errorf("FAILED: parseMoney(%q) Expected %s, got %s (%v)", input, expected, money, err)
+		}
+	}
+
+	for _, input := range []string{"", "abc", "1/3", "1e5", "1.2.3"} {
+		if _, err := parseMoney(input, "USD"); err == nil {
+			t.Errorf("FAILED: parseMoney(%q) Expected an error", input)
+		}
+	}
+}
+
+func TestMoneyRound(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Places   int
+		Expected string
+	}{
+		{"2.345", 2, "2.35"},
+		{"-2.345", 2, "-2.35"},
+		{"2.344", 2, "2.34"},
+		{"0.5", 0, "1"},
+		{"1.005", 2, "1.01"},
+	}
+
+	for _, testCase := range cases {
+		money, _ := parseMoney(testCase.Input, "USD")
+		if rounded := money.Round(testCase.Places).String(); rounded != testCase.Expected {
+			t.Errorf("FAILED: Round(%s, %d) Expected %s, got %s", testCase.Input, testCase.Places, testCase.Expected, rounded)
+		}
+	}
+}
+
+func TestConvertLargeAmountsExactly(t *testing.T) {
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "VND": 23585, "IDR": 15000.1})
+	input := DataInput{Value: Money{Amount: big.NewRat(2358500000000, 1), Currency: "VND"}, CurrencyTo: "IDR"}
+
+	result, err := convert(input, rates)
+	expected, _, _ := parseDecimal("1500010000000")
+	if err != nil || result.Amount.Cmp(expected) != 0 || result.Currency != "IDR" {
+		t.Errorf("FAILED: convert(%v) Expected 1500010000000 IDR, got %s %s", input, result, result.Currency)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	money, _ := parseMoney("1234567890123.45", "IDR")
+	encoded, _ := json.Marshal(money)
+	if string(encoded) != `{"amount":"1234567890123.45","currency":"IDR"}` {
+		t.Errorf("FAILED: json.Marshal(%s) got %s", money, encoded)
+	}
+
+	var decoded Money
+	if err := json.Unmarshal(encoded, &decoded); err != nil || decoded.Amount.Cmp(money.Amount) != 0 {
+		t.Errorf("FAILED: json.Unmarshal(%s) got %s (%v)", encoded, decoded, err)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// cardinalWords are the English numbers written as a single word.
+var cardinalWords = map[string]int64{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9,
+	"ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16,
+	"seventeen": 17, "eighteen": 18, "nineteen": 19, "twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+	"sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+}
+
+// magnitudeWords multiply the number before them, as in "3 million",
+// "five hundred" or "2 crore".
+var magnitudeWords = map[string]int64{
+	"hundred":  100,
+	"thousand": 1_000,
+	"million":  1_000_000,
+	"billion":  1_000_000_000,
+	"trillion": 1_000_000_000_000,
+	"lakh":     100_000,
+	"lakhs":    100_000,
+	"lac":      100_000,
+	"crore":    10_000_000,
+	"crores":   10_000_000,
+}
+
+// magnitudeSuffixes are the abbreviations written after digits, as in
+// "2.5k" or "1.2bn".
+var magnitudeSuffixes = map[string]int64{
+	"k":  1_000,
+	"m":  1_000_000,
+	"mn": 1_000_000,
+	"bn": 1_000_000_000,
+	"tn": 1_000_000_000_000,
+}
+
+// fractionWords are the parts of one that can be named, as in "half a
+// million" or "three quarters".
+var fractionWords = map[string]*big.Rat{
+	"half":     big.NewRat(1, 2),
+	"halves":   big.NewRat(1, 2),
+	"quarter":  big.NewRat(1, 4),
+	"quarters": big.NewRat(1, 4),
+}
+
+// digitMagnitude is the multiplier named by the token at pos, which
+// follows an amount written in digits, if it names one.
+func digitMagnitude(tokens []Token, pos int) (int64, bool) {
+	if pos >= len(tokens) || tokens[pos].Kind != TokenWord {
+		return 0, false
+	}
+	word := strings.ToLower(tokens[pos].Text)
+	if magnitude, ok := magnitudeWords[word]; ok {
+		return magnitude, true
+	}
+	magnitude, ok := magnitudeSuffixes[word]
+	return magnitude, ok
+}
+
+// scanNumberWords reads an amount written in words starting at pos, such
+// as "five hundred", "twenty-five thousand", "one and a half" or "a
+// quarter", returning it and the number of tokens it took.
+func scanNumberWords(tokens []Token, pos int) (*big.Rat, int, bool) {
+	total, current := new(big.Rat), new(big.Rat)
+	one := big.NewRat(1, 1)
+	found := false
+
+	i := pos
+	for i < len(tokens) && tokens[i].Kind == TokenWord {
+		word := strings.ToLower(tokens[i].Text)
+
+		if value, ok := cardinalValue(word); ok {
+			current.Add(current, big.NewRat(value, 1))
+			found = true
+			i++
+			continue
+		}
+		if magnitude, ok := magnitudeWords[word]; ok && (found || current.Sign() != 0) {
+			if current.Sign() == 0 {
+				current.Set(one)
+			}
+			current.Mul(current, big.NewRat(magnitude, 1))
+			found = true
+			if magnitude > 100 {
+				total.Add(total, current)
+				current = new(big.Rat)
+			}
+			i++
+			continue
+		}
+		if fraction, ok := fractionWords[word]; ok {
+			if current.Sign() == 0 {
+				current.Set(one)
+			}
+			current.Mul(current, fraction)
+			found = true
+			i++
+			continue
+		}
+
+		switch {
+		// "a hundred", "a quarter", and the "a" in "half a million".
+		case isArticle(tokens, i) && namesQuantity(tokens, i+1):
+			if current.Sign() == 0 && !found {
+				current.Set(one)
+			}
+			i++
+			continue
+		// "one and a half".
+		case word == "and" && found && isArticle(tokens, i+1) && i+2 < len(tokens) && fractionWords[strings.ToLower(tokens[i+2].Text)] != nil:
+			current.Add(current, fractionWords[strings.ToLower(tokens[i+2].Text)])
+			i += 3
+			continue
+		// "a quarter of a million".
+		case word == "of" && found && (namesQuantity(tokens, i+1) || isArticle(tokens, i+1) && namesQuantity(tokens, i+2)):
+			i++
+			continue
+		// "one hundred and fifty".
+		case word == "and" && found && i+1 < len(tokens) && isCardinalWord(tokens[i+1]):
+			i++
+			continue
+		}
+		break
+	}
+
+	if !found {
+		return nil, 0, false
+	}
+	return total.Add(total, current), i - pos, true
+}
+
+// cardinalValue reads a number word, including hyphenated ones such as
+// "twenty-five".
+func cardinalValue(word string) (int64, bool) {
+	var value int64
+	for _, part := range strings.Split(word, "-") {
+		partValue, ok := cardinalWords[part]
+		if !ok {
+			return 0, false
+		}
+		value += partValue
+	}
+	return value, true
+}
+
+func isCardinalWord(token Token) bool {
+	_, ok := cardinalValue(strings.ToLower(token.Text))
+	return token.Kind == TokenWord && ok
+}
+
+func isArticle(tokens []Token, pos int) bool {
+	return pos < len(tokens) && tokens[pos].Kind == TokenWord && (strings.EqualFold(tokens[pos].Text, "a") || strings.EqualFold(tokens[pos].Text, "an"))
+}
+
+// namesQuantity reports whether the token at pos is a magnitude or a
+// fraction, which "a" can stand before.
+func namesQuantity(tokens []Token, pos int) bool {
+	if pos >= len(tokens) || tokens[pos].Kind != TokenWord {
+		return false
+	}
+	word := strings.ToLower(tokens[pos].Text)
+	return magnitudeWords[word] != 0 || fractionWords[word] != nil
+}
+
+// decimalScale is the number of decimal places needed to write amount
+// exactly, up to the 18 digits any currency or asset is counted in.
+func decimalScale(amount *big.Rat) int {
+	denominator := amount.Denom()
+	power := big.NewInt(1)
+	ten := big.NewInt(10)
+	for scale := 0; scale < 18; scale++ {
+		if new(big.Int).Mod(power, denominator).Sign() == 0 {
+			return scale
+		}
+		power.Mul(power, ten)
+	}
+	return 18
+}
+
+// groupingMarks only ever separate groups of digits.
+const groupingMarks = "'’" + groupingSpaces
+
+// readNumber rewrites a number written with grouping and decimal marks,
+// such as "1.234,56", "1 234,56", "1'234.50" or "1,00,000", as plain
+// digits and a decimal point. When both "." and "," appear, the last is
+// the decimal mark. A single one before exactly three digits, as in
+// "1,234", could be either: decimal, the locale's decimal mark, settles
+// it, and when it is zero both readings are returned, grouped first.
+func readNumber(text string, decimal rune) ([]string, error) {
+	sign := ""
+	if rest, ok := strings.CutPrefix(text, "-"); ok {
+		sign, text = "-", rest
+	}
+
+	dots, commas := strings.Count(text, "."), strings.Count(text, ",")
+	var mark rune
+	switch {
+	case dots > 0 && commas > 0:
+		mark = rune(text[max(strings.LastIndex(text, "."), strings.LastIndex(text, ","))])
+	case dots+commas == 1:
+		mark = '.'
+		if commas == 1 {
+			mark = ','
+		}
+		whole, fraction, _ := strings.Cut(text, string(mark))
+		grouped := strings.ContainsAny(whole, groupingMarks)
+		if len(fraction) == 3 && whole != "0" && len(whole) <= 3 && !grouped {
+			switch decimal {
+			case mark:
+			case 0:
+				asDecimal, err := splitNumber(text, mark)
+				if err != nil {
+					return nil, err
+				}
+				asGrouped, err := splitNumber(text, 0)
+				if err != nil {
+					return nil, err
+				}
+				return []string{sign + asGrouped, sign + asDecimal}, nil
+			default:
+				mark = 0
+			}
+		}
+	}
+
+	number, err := splitNumber(text, mark)
+	if err != nil {
+		return nil, err
+	}
+	return []string{sign + number}, nil
+}
+
+// splitNumber reads text with mark as its decimal mark, or none when mark
+// is zero, and every other mark grouping digits in threes, or in twos
+// before the last three in the Indian style of "1,00,000".
+func splitNumber(text string, mark rune) (string, error) {
+	whole, fraction := text, ""
+	if mark != 0 {
+		index := strings.LastIndex(text, string(mark))
+		whole, fraction = text[:index], text[index+1:]
+		if strings.ContainsRune(whole, mark) || strings.ContainsAny(fraction, ".,"+groupingMarks) {
+			return "", fmt.Errorf("malformed number %q", text)
+		}
+	}
+
+	groups := strings.FieldsFunc(whole, func(char rune) bool {
+		return strings.ContainsRune(".,"+groupingMarks, char)
+	})
+	if len(groups) > 1 {
+		for i, group := range groups {
+			valid := len(group) == 3 || i == 0 && len(group) < 3 || i < len(groups)-1 && len(group) == 2
+			if !valid {
+				return "", fmt.Errorf("malformed number %q", text)
+			}
+		}
+	}
+
+	number := strings.Join(groups, "")
+	if fraction != "" {
+		number += "." + fraction
+	}
+	return number, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+)
+
+func TestParseAmountWords(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Amount string
+		Scale  int
+	}{
+		{"five hundred euros in yen", "500", 0},
+		{"twenty-five USD in EUR", "25", 0},
+		{"one hundred and fifty USD to GBP", "150", 0},
+		{"one million two hundred thousand JPY to USD", "1200000", 0},
+		{"a hundred bucks in quid", "100", 0},
+		{"half a million USD to EUR", "500000", 0},
+		{"a quarter of a million GBP in EUR", "250000", 0},
+		{"three quarters BTC in USD", "0.75", 2},
+		{"one and a half EUR to USD", "1.5", 1},
+		{"half EUR in USD", "0.5", 1},
+		{"2.5k USD to GBP", "2500", 0},
+		{"3 million JPY to USD", "3000000", 0},
+		{"1.2bn INR in USD", "1200000000", 0},
+		{"10 lakh INR to USD", "1000000", 0},
+		{"2 crore INR in USD", "20000000", 0},
+		{"0.0015k USD to EUR", "1.5", 1},
+		{"USD 4 thousand to EUR", "4000", 0},
+	}
+
+	t.Setenv("LOCALE", "en-US")
+	for _, testCase := range cases {
+		query, err := parseQuery(testCase.Input)
+		if err != nil {
+			t.Errorf("FAILED: parseQuery(%q) returned %v", testCase.Input, err)
+			continue
+		}
+		if amount := (Money{Amount: query.Amount, Scale: query.Scale}).String(); amount != testCase.Amount || query.Scale != testCase.Scale {
+			t.Errorf("FAILED: %q read as %s (scale %d), expected %s (scale %d)", testCase.Input, amount, query.Scale, testCase.Amount, testCase.Scale)
+		}
+	}
+
+	query, err := parseQuery("how much USD for five hundred EUR")
+	if err != nil || query.Intent != IntentReverse || query.Amount.Cmp(newMoney(500, "").Amount) != 0 {
+		t.Errorf("FAILED: Expected a reverse query for 500 EUR, got %+v (%v)", query, err)
+	}
+}
+
+func TestReadNumber(t *testing.T) {
+	cases := []struct {
+		Text     string
+		Decimal  rune
+		Readings []string
+	}{
+		{"1.234,56", 0, []string{"1234.56"}},
+		{"1,234.56", 0, []string{"1234.56"}},
+		{"1 234,56", 0, []string{"1234.56"}},
+		{"1 234,56", ',', []string{"1234.56"}},
+		{"1'234.50", 0, []string{"1234.50"}},
+		{"1,00,000", 0, []string{"100000"}},
+		{"12,34,567.89", '.', []string{"1234567.89"}},
+		{"1.234.567", 0, []string{"1234567"}},
+		{"12,5", 0, []string{"12.5"}},
+		{"0,125", 0, []string{"0.125"}},
+		{"1234,567", 0, []string{"1234.567"}},
+		{"1,234", '.', []string{"1234"}},
+		{"1,234", ',', []string{"1.234"}},
+		{"1.234", ',', []string{"1234"}},
+		{"1,234", 0, []string{"1234", "1.234"}},
+		{"-1.234", 0, []string{"-1234", "-1.234"}},
+	}
+
+	for _, testCase := range cases {
+		readings, err := readNumber(testCase.Text, testCase.Decimal)
+		if err != nil || !slices.Equal(readings, testCase.Readings) {
+			t.Errorf("FAILED: readNumber(%q, %q) = %v (%v), expected %v", testCase.Text, testCase.Decimal, readings, err, testCase.Readings)
+		}
+	}
+
+	for _, text := range []string{"1,23,4", "1.234,5.6", "1'23.5"} {
+		if readings, err := readNumber(text, '.'); err == nil {
+			t.Errorf("FAILED: Expected %q to be malformed, got %v", text, readings)
+		}
+	}
+}
+
+func TestParseLocalizedAmounts(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Locale string
+		Amount string
+	}{
+		{"1.234,56 EUR to USD", "de-DE", "1234.56"},
+		{"1 234,56 EUR en USD", "fr-FR", "1234.56"},
+		{"1'234.50 CHF to EUR", "de-CH", "1234.50"},
+		{"1,00,000 INR to USD", "hi-IN", "100000"},
+		{"1,234 EUR to USD", "en-GB", "1234"},
+		{"1,234 EUR to USD", "de-DE", "1.234"},
+	}
+
+	for _, testCase := range cases {
+		query, err := parseQueryWith(testCase.Input, ParseOptions{Locale: parseLocale(testCase.Locale)})
+		if err != nil {
+			t.Errorf("FAILED: parseQueryWith(%q, %s) returned %v", testCase.Input, testCase.Locale, err)
+			continue
+		}
+		if amount := (Money{Amount: query.Amount, Scale: query.Scale}).String(); amount != testCase.Amount {
+			t.Errorf("FAILED: %q in %s read as %s, expected %s", testCase.Input, testCase.Locale, amount, testCase.Amount)
+		}
+	}
+
+	_, err := parseQueryWith("1,234 EUR to USD", ParseOptions{})
+	var ambiguity *AmbiguityError
+	if !errors.As(err, &ambiguity) || ambiguity.Kind != AmbiguousAmount || !slices.Equal(ambiguity.Candidates, []string{"1234", "1.234"}) {
+		t.Fatalf("FAILED: Expected \"1,234\" to be ambiguous without a locale, got %v", err)
+	}
+	query, err := parseQueryWith("1,234 EUR to USD", ParseOptions{Resolutions: map[int]string{0: "1.234"}})
+	if err != nil || query.Amount.Cmp(big.NewRat(1234, 1000)) != 0 {
+		t.Errorf("FAILED: Expected the answer 1.234 to settle \"1,234\", got %v (%v)", query.Amount, err)
+	}
+}
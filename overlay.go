@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// overlayMutex serializes changes to the overlay store between API
+// requests. Reads go through loadConfigFile and do not take it.
+var overlayMutex sync.Mutex
+
+// errShippedOverlay is returned for a delete of an overlay that only
+// exists in OVERLAY_FILE, which is never written to.
+var errShippedOverlay = errors.New("overlay ships in OVERLAY_FILE and cannot be deleted")
+
+// Overlay is a named set of rates, such as monthly budget rates or a rate
+// negotiated in a contract, that replaces market data for its pairs.
+type Overlay struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Rates       []OverlayRate `json:"rates"`
+}
+
+// OverlayRate prices one unit of From in To between ValidFrom and ValidTo,
+// both inclusive and in YYYY-MM-DD form. Either bound may be left open.
+type OverlayRate struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Rate      string `json:"rate"`
+	ValidFrom string `json:"valid_from,omitempty"`
+	ValidTo   string `json:"valid_to,omitempty"`
+}
+
+// overlayStorePath is where overlays edited over the API are kept: the file
+// named by OVERLAY_STORE, or overlays.json in the user's configuration
+// directory. It is kept apart from OVERLAY_FILE, which ships with the code
+// and is only read.
+func overlayStorePath() (string, error) {
+	if fileName := getEnvVar("OVERLAY_STORE"); fileName != "" {
+		return fileName, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("OVERLAY_STORE is not configured: %w", err)
+	}
+	return filepath.Join(dir, "currencyconverter", "overlays.json"), nil
+}
+
+// loadOverlays lists the overlays of OVERLAY_FILE and of the store. A
+// stored overlay replaces the shipped one of the same name, and the rest
+// of the shipped overlays stay in effect whatever the store holds.
+func loadOverlays() ([]Overlay, error) {
+	stored, err := loadStoredOverlays()
+	if err != nil {
+		return nil, err
+	}
+	shipped, err := loadShippedOverlays()
+	if err != nil {
+		return nil, err
+	}
+
+	var overlays []Overlay
+	for _, overlay := range shipped {
+		if !slices.ContainsFunc(stored, func(other Overlay) bool { return other.Name == overlay.Name }) {
+			overlays = append(overlays, overlay)
+		}
+	}
+	return append(overlays, stored...), nil
+}
+
+func loadShippedOverlays() ([]Overlay, error) {
+	fileName := getEnvVar("OVERLAY_FILE")
+	if fileName == "" {
+		return nil, nil
+	}
+	return readOverlayFile(fileName)
+}
+
+// loadStoredOverlays reads the overlay store. When no store can be found,
+// as without a home directory, it holds nothing; only saving fails.
+func loadStoredOverlays() ([]Overlay, error) {
+	fileName, err := overlayStorePath()
+	if err != nil {
+		return nil, nil
+	}
+	return readOverlayFile(fileName)
+}
+
+// readOverlayFile decodes fileName, which is only read again once it
+// changes on disk, as an overlay may be applied to every conversion. A
+// file that does not exist holds no overlays.
+func readOverlayFile(fileName string) ([]Overlay, error) {
+	overlays, err := loadConfigFile(fileName, func(data []byte) ([]Overlay, error) {
+		var overlays []Overlay
+		if err := json.Unmarshal(data, &overlays); err != nil {
+			return nil, fmt.Errorf("failed to parse overlay file: %w", err)
+		}
+		return overlays, nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load overlay file: %w", err)
+	}
+	return overlays, nil
+}
+
+func saveOverlays(overlays []Overlay) error {
+	fileName, err := overlayStorePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(overlays, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	// A write within the file system's timestamp resolution could leave
+	// the modification time and size unchanged, so the cached copy is
+	// dropped rather than trusted to go stale.
+	defer forgetConfigFile(fileName)
+	return os.WriteFile(fileName, append(data, '\n'), 0644)
+}
+
+func getOverlay(name string) (Overlay, error) {
+	overlays, err := loadOverlays()
+	if err != nil {
+		return Overlay{}, err
+	}
+	for _, overlay := range overlays {
+		if overlay.Name == name {
+			return overlay, nil
+		}
+	}
+	return Overlay{}, &ConversionError{Kind: ErrUnknownOverlay, Detail: name}
+}
+
+func listOverlays() ([]Overlay, error) {
+	return loadOverlays()
+}
+
+// putOverlay validates overlay and stores it, replacing any overlay with the
+// same name.
+func putOverlay(overlay Overlay) error {
+	if err := overlay.validate(); err != nil {
+		return err
+	}
+
+	overlayMutex.Lock()
+	defer overlayMutex.Unlock()
+
+	stored, err := loadStoredOverlays()
+	if err != nil {
+		return err
+	}
+
+	// The cached slice is shared, so the new list is built in a copy.
+	overlays := slices.Clone(stored)
+	index := slices.IndexFunc(overlays, func(other Overlay) bool { return other.Name == overlay.Name })
+	if index >= 0 {
+		overlays[index] = overlay
+	} else {
+		overlays = append(overlays, overlay)
+	}
+
+	return saveOverlays(overlays)
+}
+
+// deleteOverlay removes the stored overlay called name, which brings back
+// a shipped overlay of the same name.
+func deleteOverlay(name string) error {
+	overlayMutex.Lock()
+	defer overlayMutex.Unlock()
+
+	stored, err := loadStoredOverlays()
+	if err != nil {
+		return err
+	}
+
+	index := slices.IndexFunc(stored, func(overlay Overlay) bool { return overlay.Name == name })
+	if index >= 0 {
+		return saveOverlays(slices.Delete(slices.Clone(stored), index, index+1))
+	}
+
+	shipped, err := loadShippedOverlays()
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(shipped, func(overlay Overlay) bool { return overlay.Name == name }) {
+		return errShippedOverlay
+	}
+	return &ConversionError{Kind: ErrUnknownOverlay, Detail: name}
+}
+
+func (overlay Overlay) validate() error {
+	if overlay.Name == "" {
+		return errors.New("overlay name is required")
+	}
+
+	for _, entry := range overlay.Rates {
+		if !checkValidCurrency(entry.From) || !checkValidCurrency(entry.To) || entry.From == entry.To {
+			return fmt.Errorf("invalid overlay pair %s/%s", entry.From, entry.To)
+		}
+		rate, _, err := parseDecimal(entry.Rate)
+		if err != nil || rate.Sign() <= 0 {
+			return fmt.Errorf("invalid overlay rate %q for %s/%s", entry.Rate, entry.From, entry.To)
+		}
+		for _, date := range []string{entry.ValidFrom, entry.ValidTo} {
+			if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+				return fmt.Errorf("invalid overlay date %q, please use YYYY-MM-DD", date)
+			}
+		}
+		if entry.ValidFrom != "" && entry.ValidTo != "" && entry.ValidTo < entry.ValidFrom {
+			return fmt.Errorf("overlay rate for %s/%s ends on %s, before it starts on %s", entry.From, entry.To, entry.ValidTo, entry.ValidFrom)
+		}
+	}
+
+	return nil
+}
+
+// rate finds the overlay's rate for from into to on date, using the inverse
+// of a to/from entry when only that direction was given.
+func (overlay Overlay) rate(from string, to string, date string) (*big.Rat, bool) {
+	if date == "" {
+		date = time.Now().UTC().Format("2006-01-02")
+	}
+
+	for _, entry := range overlay.Rates {
+		// Dates in YYYY-MM-DD form compare correctly as strings.
+		if (entry.ValidFrom != "" && date < entry.ValidFrom) || (entry.ValidTo != "" && date > entry.ValidTo) {
+			continue
+		}
+
+		rate, _, err := parseDecimal(entry.Rate)
+		if err != nil || rate.Sign() <= 0 {
+			continue
+		}
+		if entry.From == from && entry.To == to {
+			return rate, true
+		}
+		if entry.From == to && entry.To == from {
+			return rate.Inv(rate), true
+		}
+	}
+
+	return nil, false
+}
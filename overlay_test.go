@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestOverlayRate(t *testing.T) {
+	overlay := Overlay{Name: "contract", Rates: []OverlayRate{
+		{From: "EUR", To: "USD", Rate: "1.25", ValidFrom: "2024-01-01", ValidTo: "2024-06-30"},
+		{From: "EUR", To: "USD", Rate: "1.10", ValidFrom: "2024-07-01"},
+	}}
+
+	cases := []struct {
+		From     string
+		To       string
+		Date     string
+		Expected string
+	}{
+		{"EUR", "USD", "2024-03-15", "1.25"},
+		{"EUR", "USD", "2024-06-30", "1.25"},
+		{"EUR", "USD", "2025-01-01", "1.1"},
+		{"USD", "EUR", "2024-03-15", "0.8"},
+		{"EUR", "USD", "2023-12-31", ""},
+		{"EUR", "GBP", "2024-03-15", ""},
+	}
+
+	for _, testCase := range cases {
+		rate, ok := overlay.rate(testCase.From, testCase.To, testCase.Date)
+		if testCase.Expected == "" && ok {
+			t.Errorf("FAILED: rate(%s, %s, %s) Expected no overlay rate, got %s", testCase.From, testCase.To, testCase.Date, formatRate(rate))
+		} else if testCase.Expected != "" && (!ok || formatRate(rate) != testCase.Expected) {
+			t.Errorf("FAILED: rate(%s, %s, %s) Expected %s, got %v", testCase.From, testCase.To, testCase.Date, testCase.Expected, rate)
+		}
+	}
+}
+
+func TestConvertWithOverlay(t *testing.T) {
+	t.Setenv("OVERLAY_FILE", filepath.Join(t.TempDir(), "overlays.json"))
+	t.Setenv("OVERLAY_STORE", filepath.Join(t.TempDir(), "store", "overlays.json"))
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9})
+
+	budget := Overlay{Name: "budget", Rates: []OverlayRate{{From: "EUR", To: "USD", Rate: "1.08"}}}
+	if err := putOverlay(budget); err != nil {
+		t.Fatalf("FAILED: putOverlay returned %v", err)
+	}
+
+	quote, err := priceConversion(DataInput{Value: newMoney(100, "EUR"), CurrencyTo: "USD", Overlay: "budget"}, rates)
+	if err != nil || quote.Overlay != "budget" || quote.Net.String() != "108.00" {
+		t.Errorf("FAILED: Expected 108.00 USD at the budget rate, got %s via %q (%v)", quote.Net, quote.Overlay, err)
+	}
+	provenance, err := rates.provenance(DataInput{Value: newMoney(100, "EUR"), CurrencyTo: "USD", Overlay: "budget"})
+	if err != nil || provenance.Rate != "1.08" || provenance.MidRate != formatRate(big.NewRat(10, 9)) {
+		t.Errorf("FAILED: Expected the budget rate 1.08 applied beside the market's %s, got %+v (%v)", formatRate(big.NewRat(10, 9)), provenance, err)
+	}
+
+	quote, err = priceConversion(DataInput{Value: newMoney(90, "USD"), CurrencyTo: "EUR", Overlay: "budget"}, newRateSnapshot("USD", map[string]float64{"USD": 1}))
+	if err != nil || quote.Overlay != "budget" || quote.Net.String() != "83.33" {
+		t.Errorf("FAILED: Expected the overlay to cover a pair without market rates, got %s (%v)", quote.Net, err)
+	}
+
+	if err := deleteOverlay("budget"); err != nil {
+		t.Errorf("FAILED: deleteOverlay returned %v", err)
+	}
+	if _, err := convert(DataInput{Value: newMoney(1, "EUR"), CurrencyTo: "USD", Overlay: "budget"}, rates); !errors.Is(err, ErrUnknownOverlay) {
+		t.Errorf("FAILED: Expected unknown overlay after deleting it, got %v", err)
+	}
+}
+
+func TestOverlayValidation(t *testing.T) {
+	invalid := []Overlay{
+		{Name: ""},
+		{Name: "bad", Rates: []OverlayRate{{From: "EUR", To: "EUR", Rate: "1"}}},
+		{Name: "bad", Rates: []OverlayRate{{From: "EUR", To: "USD", Rate: "-1"}}},
+		{Name: "bad", Rates: []OverlayRate{{From: "EUR", To: "USD", Rate: "1", ValidFrom: "01/01/2024"}}},
+		{Name: "bad", Rates: []OverlayRate{{From: "EUR", To: "USD", Rate: "1", ValidFrom: "2024-06-30", ValidTo: "2024-01-01"}}},
+	}
+
+	for _, overlay := range invalid {
+		if err := overlay.validate(); err == nil {
+			t.Errorf("FAILED: validate(%+v) Expected an error", overlay)
+		}
+	}
+}
+
+func TestOverlayStore(t *testing.T) {
+	seed := filepath.Join(t.TempDir(), "overlays.json")
+	seedData := []byte(`[{"name": "contract", "rates": [{"from": "EUR", "to": "USD", "rate": "1.10"}]}]` + "\n")
+	if err := os.WriteFile(seed, seedData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OVERLAY_FILE", seed)
+	t.Setenv("OVERLAY_STORE", filepath.Join(t.TempDir(), "store", "overlays.json"))
+
+	if overlays, err := listOverlays(); err != nil || len(overlays) != 1 {
+		t.Fatalf("FAILED: Expected the overlay from OVERLAY_FILE before any edit, got %v (%v)", overlays, err)
+	}
+	if err := putOverlay(Overlay{Name: "budget", Rates: []OverlayRate{{From: "EUR", To: "USD", Rate: "1.08"}}}); err != nil {
+		t.Fatalf("FAILED: putOverlay returned %v", err)
+	}
+	if overlays, err := listOverlays(); err != nil || len(overlays) != 2 {
+		t.Errorf("FAILED: Expected both overlays in the store, got %v (%v)", overlays, err)
+	}
+	if data, _ := os.ReadFile(seed); !bytes.Equal(data, seedData) {
+		t.Errorf("FAILED: Expected OVERLAY_FILE to be left alone, got %s", data)
+	}
+
+	// Shipped overlays stay in effect, and follow edits to OVERLAY_FILE,
+	// once the store exists.
+	shippedData := []byte(`[{"name": "contract", "rates": [{"from": "EUR", "to": "USD", "rate": "1.12"}]}, {"name": "audit", "rates": []}]` + "\n")
+	if err := os.WriteFile(seed, shippedData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if overlay, err := getOverlay("contract"); err != nil || overlay.Rates[0].Rate != "1.12" {
+		t.Errorf("FAILED: Expected the edited shipped contract overlay, got %+v (%v)", overlay, err)
+	}
+
+	// A stored overlay takes the place of the shipped one of its name until
+	// it is deleted.
+	if err := putOverlay(Overlay{Name: "contract", Rates: []OverlayRate{{From: "EUR", To: "USD", Rate: "1.20"}}}); err != nil {
+		t.Fatalf("FAILED: putOverlay returned %v", err)
+	}
+	if overlay, err := getOverlay("contract"); err != nil || overlay.Rates[0].Rate != "1.20" {
+		t.Errorf("FAILED: Expected the stored contract overlay to win, got %+v (%v)", overlay, err)
+	}
+	for _, name := range []string{"contract", "budget"} {
+		if err := deleteOverlay(name); err != nil {
+			t.Errorf("FAILED: deleteOverlay(%s) returned %v", name, err)
+		}
+	}
+	if overlays, err := listOverlays(); err != nil || len(overlays) != 2 || overlays[0].Rates[0].Rate != "1.12" {
+		t.Errorf("FAILED: Expected the shipped overlays once the store is empty, got %v (%v)", overlays, err)
+	}
+	if err := deleteOverlay("audit"); !errors.Is(err, errShippedOverlay) {
+		t.Errorf("FAILED: Expected a shipped overlay to refuse deletion, got %v", err)
+	}
+}
+
+func TestOverlayStoreUnconfigured(t *testing.T) {
+	seed := filepath.Join(t.TempDir(), "overlays.json")
+	if err := os.WriteFile(seed, []byte(`[{"name": "contract", "rates": [{"from": "EUR", "to": "USD", "rate": "1.10"}]}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OVERLAY_FILE", seed)
+	t.Setenv("OVERLAY_STORE", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "")
+	if _, err := os.UserConfigDir(); err == nil {
+		t.Skip("the user configuration directory does not depend on HOME here")
+	}
+
+	if overlay, err := getOverlay("contract"); err != nil || overlay.Name != "contract" {
+		t.Errorf("FAILED: Expected the shipped overlay without a store, got %+v (%v)", overlay, err)
+	}
+	if err := putOverlay(Overlay{Name: "budget"}); err == nil {
+		t.Errorf("FAILED: Expected saving without a store to fail")
+	}
+}
+
+func TestRequireOverlayToken(t *testing.T) {
+	e := echo.New()
+	handler := requireOverlayToken(func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	cases := []struct {
+		Token         string
+		Authorization string
+		Expected      int
+	}{
+		{"", "Bearer anything", http.StatusForbidden},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"secret", "secret", http.StatusUnauthorized},
+		{"secret", "Bearer secret", http.StatusNoContent},
+	}
+
+	for _, testCase := range cases {
+		t.Setenv("OVERLAY_ADMIN_TOKEN", testCase.Token)
+		req := httptest.NewRequest(http.MethodPut, "/overlays/budget", nil)
+		if testCase.Authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, testCase.Authorization)
+		}
+		rec := httptest.NewRecorder()
+		if err := handler(e.NewContext(req, rec)); err != nil || rec.Code != testCase.Expected {
+			t.Errorf("FAILED: token %q with %q Expected status %d, got %d (%v)", testCase.Token, testCase.Authorization, testCase.Expected, rec.Code, err)
+		}
+	}
+}
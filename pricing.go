@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// rateDisplayPlaces is how many fractional digits rates are reported with.
+const rateDisplayPlaces = 8
+
+// FeeSchedule turns a mid-market conversion into what a customer actually
+// receives. Spread is taken off the mid rate; fees are charged in the
+// source currency before converting, as a percentage plus a fixed amount
+// and never less than MinimumFee. FixedFee and MinimumFee are amounts of
+// FeeCurrency, restated in the source currency at the conversion's rates.
+type FeeSchedule struct {
+	Spread      *big.Rat
+	FeeRate     *big.Rat
+	FixedFee    *big.Rat
+	MinimumFee  *big.Rat
+	FeeCurrency string
+}
+
+type feeScheduleConfig struct {
+	SpreadPercent string `json:"spread_percent"`
+	FeePercent    string `json:"fee_percent"`
+	FixedFee      string `json:"fixed_fee"`
+	MinimumFee    string `json:"minimum_fee"`
+	FeeCurrency   string `json:"fee_currency"`
+}
+
+type pricingProfileConfig struct {
+	feeScheduleConfig
+	Pairs map[string]feeScheduleConfig `json:"pairs"`
+}
+
+// PricingConfig is read from PRICING_FILE. Pairs apply when no profile is
+// requested; a profile's own pairs override its default schedule. Pairs are
+// keyed as "USD/EUR".
+type PricingConfig struct {
+	Pairs    map[string]feeScheduleConfig    `json:"pairs"`
+	Profiles map[string]pricingProfileConfig `json:"profiles"`
+}
+
+// Quote is the outcome of pricing a conversion. Fees are in the source
+// currency and Net is what is received after fees and spread. MidRate comes
+// from Overlay instead of the market when one applied, and Route names the
+// path through quoted pairs when the rate table could not price it.
+type Quote struct {
+	Profile       string
+	Overlay       string
+	Route         string
+	MidRate       *big.Rat
+	EffectiveRate *big.Rat
+	Fees          Money
+	Net           Money
+}
+
+// loadPricingConfig reads PRICING_FILE, which is only decoded again once it
+// changes.
+func loadPricingConfig() (PricingConfig, error) {
+	fileName := getEnvVar("PRICING_FILE")
+	if fileName == "" {
+		return PricingConfig{}, nil
+	}
+
+	config, err := loadConfigFile(fileName, func(data []byte) (PricingConfig, error) {
+		var config PricingConfig
+		err := json.Unmarshal(data, &config)
+		return config, err
+	})
+	if err != nil {
+		return config, fmt.Errorf("failed to load pricing file: %w", err)
+	}
+	return config, nil
+}
+
+// feeScheduleFor finds the schedule for a pair, preferring the pair entry of
+// the requested profile, then the profile default. Without a profile the
+// top-level pair entry applies, and otherwise the conversion is free.
+func feeScheduleFor(profile string, from string, to string) (FeeSchedule, error) {
+	config, err := loadPricingConfig()
+	if err != nil {
+		return FeeSchedule{}, err
+	}
+
+	pair := from + "/" + to
+
+	if profile == "" {
+		return parseFeeSchedule(config.Pairs[pair])
+	}
+
+	profileConfig, ok := config.Profiles[profile]
+	if !ok {
+		return FeeSchedule{}, &ConversionError{Kind: ErrUnknownProfile, Detail: profile}
+	}
+	if pairConfig, ok := profileConfig.Pairs[pair]; ok {
+		return parseFeeSchedule(pairConfig)
+	}
+	return parseFeeSchedule(profileConfig.feeScheduleConfig)
+}
+
+func parseFeeSchedule(config feeScheduleConfig) (FeeSchedule, error) {
+	var parse = func(field string, value string, scale *big.Rat) (*big.Rat, error) {
+		if value == "" {
+			return new(big.Rat), nil
+		}
+		parsed, _, err := parseDecimal(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in pricing file: %w", field, err)
+		}
+		if parsed.Sign() < 0 {
+			return nil, fmt.Errorf("invalid %s in pricing file: must not be negative", field)
+		}
+		return parsed.Mul(parsed, scale), nil
+	}
+
+	percent := big.NewRat(1, 100)
+	one := big.NewRat(1, 1)
+	var schedule FeeSchedule
+	var err error
+
+	if schedule.Spread, err = parse("spread_percent", config.SpreadPercent, percent); err != nil {
+		return schedule, err
+	}
+	if schedule.FeeRate, err = parse("fee_percent", config.FeePercent, percent); err != nil {
+		return schedule, err
+	}
+	if schedule.Spread.Cmp(one) >= 0 || schedule.FeeRate.Cmp(one) >= 0 {
+		return schedule, fmt.Errorf("invalid pricing file: percentages must be below 100")
+	}
+	if schedule.FixedFee, err = parse("fixed_fee", config.FixedFee, one); err != nil {
+		return schedule, err
+	}
+	if schedule.MinimumFee, err = parse("minimum_fee", config.MinimumFee, one); err != nil {
+		return schedule, err
+	}
+
+	schedule.FeeCurrency = config.FeeCurrency
+	if schedule.FeeCurrency == "" && (schedule.FixedFee.Sign() != 0 || schedule.MinimumFee.Sign() != 0) {
+		return schedule, fmt.Errorf("invalid pricing file: fixed_fee and minimum_fee need a fee_currency")
+	}
+	if _, ok := lookupCurrency(schedule.FeeCurrency); schedule.FeeCurrency != "" && !ok {
+		return schedule, fmt.Errorf("invalid fee_currency %q in pricing file", schedule.FeeCurrency)
+	}
+
+	return schedule, nil
+}
+
+// inCurrency restates the fixed and minimum fees in currency, converting
+// them from the fee currency at the snapshot's rates on date.
+func (schedule FeeSchedule) inCurrency(currency string, date string, snapshot RateSnapshot) (FeeSchedule, error) {
+	if schedule.FeeCurrency == "" || schedule.FeeCurrency == currency {
+		return schedule, nil
+	}
+
+	rate, _, err := conversionRate(DataInput{Value: newMoney(1, schedule.FeeCurrency), CurrencyTo: currency, Date: date}, snapshot)
+	if err != nil {
+		return schedule, err
+	}
+	schedule.FixedFee = new(big.Rat).Mul(schedule.FixedFee, rate)
+	schedule.MinimumFee = new(big.Rat).Mul(schedule.MinimumFee, rate)
+	schedule.FeeCurrency = currency
+	return schedule, nil
+}
+
+func (schedule FeeSchedule) isFree() bool {
+	return schedule.Spread.Sign() == 0 && schedule.FeeRate.Sign() == 0 &&
+		schedule.FixedFee.Sign() == 0 && schedule.MinimumFee.Sign() == 0
+}
+
+// fees is what the schedule charges on amount, rounded up to the source
+// currency's minor units so the customer is never undercharged.
+func (schedule FeeSchedule) fees(amount Money) Money {
+	fee := new(big.Rat).Mul(amount.amount(), schedule.FeeRate)
+	fee.Add(fee, schedule.FixedFee)
+	if fee.Cmp(schedule.MinimumFee) < 0 {
+		fee.Set(schedule.MinimumFee)
+	}
+
+	return Money{Amount: fee, Currency: amount.Currency}.RoundWith(roundingFor(amount.Currency, RoundCeiling))
+}
+
+func (schedule FeeSchedule) effectiveRate(midRate *big.Rat) *big.Rat {
+	return new(big.Rat).Mul(midRate, new(big.Rat).Sub(big.NewRat(1, 1), schedule.Spread))
+}
+
+// priceConversion converts dataInput the way a bank or card network would,
+// using the fee schedule of dataInput.Profile.
+func priceConversion(dataInput DataInput, snapshot RateSnapshot) (Quote, error) {
+	quote := Quote{Profile: dataInput.Profile}
+
+	if err := validateConversion(dataInput); err != nil {
+		return quote, err
+	}
+
+	schedule, err := feeScheduleFor(dataInput.Profile, dataInput.Value.Currency, dataInput.CurrencyTo)
+	if err != nil {
+		return quote, err
+	}
+	if schedule, err = schedule.inCurrency(dataInput.Value.Currency, dataInput.Date, snapshot); err != nil {
+		return quote, err
+	}
+
+	midRate, source, err := conversionRate(dataInput, snapshot)
+	if err != nil {
+		return quote, err
+	}
+	quote.MidRate, quote.Overlay, quote.Route = midRate, source.Overlay, source.Route
+	quote.EffectiveRate = schedule.effectiveRate(quote.MidRate)
+	quote.Fees = schedule.fees(dataInput.Value)
+
+	if quote.Fees.amount().Cmp(dataInput.Value.amount()) >= 0 {
+		return quote, &ConversionError{Kind: ErrFeesExceedAmount, Currency: dataInput.Value.Currency, Detail: displayMoney(quote.Fees)}
+	}
+
+	principal := Money{Amount: new(big.Rat).Sub(dataInput.Value.amount(), quote.Fees.amount()), Currency: dataInput.Value.Currency}
+	quote.Net, err = convertAtRate(principal, dataInput.CurrencyTo, quote.EffectiveRate, dataInput.Rounding)
+	return quote, err
+}
+
+// reversePriceConversion solves for the smallest amount of
+// dataInput.Value.Currency that nets dataInput.Target once fees, spread and
+// rounding are applied. The returned DataInput carries that amount as Value.
+func reversePriceConversion(dataInput DataInput, snapshot RateSnapshot) (DataInput, Quote, error) {
+	source := dataInput.Value.Currency
+	target := dataInput.Target
+
+	check := dataInput
+	check.Value = Money{Amount: target.Amount, Currency: source}
+	if err := validateConversion(check); err != nil {
+		return dataInput, Quote{Profile: dataInput.Profile}, err
+	}
+
+	midRate, _, err := conversionRate(check, snapshot)
+	if err != nil {
+		return dataInput, Quote{Profile: dataInput.Profile}, err
+	}
+
+	schedule, err := feeScheduleFor(dataInput.Profile, source, target.Currency)
+	if err != nil {
+		return dataInput, Quote{Profile: dataInput.Profile}, err
+	}
+	if schedule, err = schedule.inCurrency(source, dataInput.Date, snapshot); err != nil {
+		return dataInput, Quote{Profile: dataInput.Profile}, err
+	}
+
+	// Invert the fee schedule exactly first: the principal has to cover
+	// target at the effective rate, and the fee is either percentage plus
+	// fixed or the minimum, whichever applies to the gross amount.
+	effectiveRate := schedule.effectiveRate(midRate)
+	principal := new(big.Rat).Quo(target.amount(), effectiveRate)
+
+	gross := new(big.Rat).Add(principal, schedule.FixedFee)
+	gross.Quo(gross, new(big.Rat).Sub(big.NewRat(1, 1), schedule.FeeRate))
+	percentageFee := new(big.Rat).Add(new(big.Rat).Mul(gross, schedule.FeeRate), schedule.FixedFee)
+	if percentageFee.Cmp(schedule.MinimumFee) < 0 {
+		gross = new(big.Rat).Add(principal, schedule.MinimumFee)
+	}
+
+	// Rounding of the fee and the net amount can leave the exact answer a
+	// minor unit or two off, so settle it by pricing neighbouring amounts.
+	step := roundingFor(source, RoundCeiling)
+	solved := dataInput
+	solved.Value = Money{Amount: gross, Currency: source}.RoundWith(step)
+
+	var reaches = func(amount *big.Rat) (Quote, bool) {
+		attempt := solved
+		attempt.Value = Money{Amount: amount, Scale: solved.Value.Scale, Currency: source}
+		quote, err := priceConversion(attempt, snapshot)
+		return quote, err == nil && quote.Net.amount().Cmp(target.amount()) >= 0
+	}
+
+	for i := 0; i < 100; i++ {
+		if _, ok := reaches(solved.Value.amount()); ok {
+			break
+		}
+		solved.Value.Amount = new(big.Rat).Add(solved.Value.amount(), step.Increment)
+	}
+	for i := 0; i < 100; i++ {
+		lower := new(big.Rat).Sub(solved.Value.amount(), step.Increment)
+		if _, ok := reaches(lower); !ok {
+			break
+		}
+		solved.Value.Amount = lower
+	}
+
+	quote, err := priceConversion(solved, snapshot)
+	if err != nil {
+		return solved, quote, err
+	}
+	if quote.Net.amount().Cmp(target.amount()) < 0 {
+		return solved, quote, &ConversionError{Kind: ErrUnreachableTarget, Currency: target.Currency, Detail: displayMoney(target)}
+	}
+
+	return solved, quote, nil
+}
+
+func formatRate(rate *big.Rat) string {
+	return rate.FloatString(min(decimalPlaces(rate), rateDisplayPlaces))
+}
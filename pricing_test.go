@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestPriceConversion(t *testing.T) {
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0.8, "JPY": 150})
+
+	cases := []struct {
+		Input         DataInput
+		EffectiveRate string
+		Fees          string
+		Net           string
+	}{
+		{DataInput{Value: newMoney(100, "USD"), CurrencyTo: "EUR"}, "0.9", "0.00", "90.00"},
+		{DataInput{Value: newMoney(100, "USD"), CurrencyTo: "EUR", Profile: "bank"}, "0.8865", "3.00", "85.99"},
+		{DataInput{Value: newMoney(100, "USD"), CurrencyTo: "GBP", Profile: "bank"}, "0.78", "5.00", "74.10"},
+		{DataInput{Value: newMoney(1000, "USD"), CurrencyTo: "EUR", Profile: "wire"}, "0.8955", "15.00", "882.07"},
+		// Fixed and minimum fees are set in USD and restated in the source
+		// currency, so the bank's 5.00 USD is 750 JPY, not 5 JPY.
+		{DataInput{Value: newMoney(100000, "JPY"), CurrencyTo: "USD", Profile: "bank"}, "0.0065", "750", "645.13"},
+		{DataInput{Value: newMoney(1000, "EUR"), CurrencyTo: "USD", Profile: "wire"}, "1.10555556", "13.50", "1090.63"},
+	}
+
+	for _, testCase := range cases {
+		quote, err := priceConversion(testCase.Input, rates)
+		if err != nil {
+			t.Errorf("FAILED: priceConversion(%v) returned %v", testCase.Input, err)
+			continue
+		}
+
+		if formatRate(quote.EffectiveRate) != testCase.EffectiveRate || displayMoney(quote.Fees) != testCase.Fees || quote.Net.String() != testCase.Net {
+			t.Errorf("FAILED: priceConversion(%v) Expected %s/%s/%s, got %s/%s/%s", testCase.Input,
+				testCase.EffectiveRate, testCase.Fees, testCase.Net,
+				formatRate(quote.EffectiveRate), displayMoney(quote.Fees), quote.Net)
+		}
+	}
+}
+
+func TestPriceConversionErrors(t *testing.T) {
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9})
+
+	_, err := priceConversion(DataInput{Value: newMoney(10, "USD"), CurrencyTo: "EUR", Profile: "wire"}, rates)
+	if !errors.Is(err, ErrFeesExceedAmount) {
+		t.Errorf("FAILED: Expected fees to exceed 10 USD, got %v", err)
+	}
+
+	_, err = priceConversion(DataInput{Value: newMoney(10, "USD"), CurrencyTo: "EUR", Profile: "casino"}, rates)
+	if !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("FAILED: Expected unknown profile, got %v", err)
+	}
+
+	if _, err := parseFeeSchedule(feeScheduleConfig{FixedFee: "5.00"}); err == nil {
+		t.Errorf("FAILED: Expected a fixed fee without a fee_currency to be rejected")
+	}
+}
+
+func TestReversePriceConversion(t *testing.T) {
+	rates := newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9, "JPY": 150})
+
+	cases := []struct {
+		Input    DataInput
+		Expected string
+	}{
+		{DataInput{Value: Money{Currency: "USD"}, Target: newMoney(90, "EUR"), CurrencyTo: "EUR"}, "100.00"},
+		{DataInput{Value: Money{Currency: "USD"}, Target: Money{Amount: big.NewRat(8599, 100), Currency: "EUR"}, CurrencyTo: "EUR", Profile: "bank"}, "100.00"},
+		// The 15.00 USD minimum fee is 13.50 EUR at these rates.
+		{DataInput{Value: Money{Currency: "EUR"}, Target: newMoney(10000, "JPY"), CurrencyTo: "JPY", Profile: "wire"}, "73.80"},
+	}
+
+	for _, testCase := range cases {
+		solved, quote, err := reversePriceConversion(testCase.Input, rates)
+		if err != nil || solved.Value.String() != testCase.Expected {
+			t.Errorf("FAILED: reversePriceConversion(%v) Expected %s, got %s (%v)", testCase.Input, testCase.Expected, solved.Value, err)
+			continue
+		}
+		if quote.Net.amount().Cmp(testCase.Input.Target.amount()) < 0 {
+			t.Errorf("FAILED: reversePriceConversion(%v) nets only %s", testCase.Input, quote.Net)
+		}
+	}
+}
@@ -0,0 +1,810 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+type QueryIntent string
+
+const (
+	IntentConvert QueryIntent = "convert"
+	// IntentReverse solves for the source amount that delivers Amount of
+	// the first target, as in "how much USD for 500 EUR".
+	IntentReverse QueryIntent = "reverse"
+)
+
+// Query is a parsed conversion query. Amount is nil when none was given.
+// For IntentReverse it is the amount of the first target to receive, and
+// UnitTo its unit. Modifiers holds the "rounding", "profile" and "overlay"
+// settings named in the query. Period is set instead of Date for a range
+// of days. Interpretations lists the symbols, names, slang and dates read
+// in words.
+//
+// Sources lists every amount of a query such as "10 EUR, 20 GBP and 3000
+// JPY to USD", the first of which is also held in Amount, Unit and Source.
+// Chain lists the currencies each result is converted on to, as in "100
+// USD to EUR then to CHF", and Total asks for the results to be added up.
+type Query struct {
+	Intent    QueryIntent
+	Amount    *big.Rat
+	Scale     int
+	Unit      string
+	Source    string
+	Targets   []string
+	UnitTo    string
+	Date      string
+	Period    DateRange
+	Modifiers map[string]string
+	Sources   []QuerySource
+	Chain     []string
+	Total     bool
+
+	Interpretations []Interpretation
+}
+
+// QuerySource is one of the amounts listed in a query.
+type QuerySource struct {
+	Amount   *big.Rat
+	Scale    int
+	Unit     string
+	Currency string
+}
+
+// Words that link the parts of a query. Any other word that is not a
+// currency, unit or modifier is filler, such as "how much is" or "convert".
+var (
+	connectorWords = []string{"TO", "IN", "INTO", "AS"}
+	reverseWords   = []string{"FOR", "GET"}
+	listWords      = []string{"AND"}
+	chainWords     = []string{"THEN"}
+)
+
+// totalWords ask for the results of a list to be added up, as in "10 EUR
+// and 20 GBP to USD in total".
+var totalWords = []string{"TOTAL", "ALTOGETHER"}
+
+// modifierWords map the keyword that introduces a modifier to its name, as
+// in "100 USD to EUR rounding half-even profile bank".
+var modifierWords = map[string]string{
+	"ROUNDING": "rounding",
+	"ROUNDED":  "rounding",
+	"PROFILE":  "profile",
+	"OVERLAY":  "overlay",
+}
+
+type queryParser struct {
+	input  string
+	tokens []Token
+	pos    int
+	query  Query
+
+	// skipped holds words passed over as filler, which are the likeliest
+	// culprits when a currency turns out to be missing.
+	skipped []Token
+
+	// consumed is the position of the last token the grammar used.
+	consumed int
+
+	options ParseOptions
+
+	// dated is set once the query has named its day, even as "today".
+	dated bool
+
+	// err is a malformed date or modifier, or an ambiguous word, met while
+	// looking ahead.
+	err error
+}
+
+// parseQuery reads a conversion query such as "How much is 100 JPY in GBP
+// on 2023-06-30?". The grammar, over the words that are not filler, is
+//
+//	query   = sources [link|"/"] targets {"then" [link] currency} |
+//	          currency link amount target
+//	sources = source {(","|"and") amount [unit] currency}
+//	source  = [amount] [unit] currency [amount]
+//	targets = target {[","|"and"|link] target}
+//	target  = [unit] currency
+//
+// where a date or range of dates, modifiers and "total" may appear
+// anywhere. The second form, with "for" or "get" as the link, asks for the
+// source amount needed.
+func parseQuery(input string) (Query, error) {
+	return parseQueryWith(input, defaultParseOptions())
+}
+
+// ParseOptions carry what a query is read against. Locale settles symbols
+// such as "$" that several currencies share, and whether "1,234" is a
+// thousand or a decimal. Resolutions holds the answers to the
+// AmbiguityErrors an earlier attempt returned, keyed by the position of the
+// word they settle; an empty code means the word is not a currency, and an
+// amount's answer is one of its readings.
+type ParseOptions struct {
+	Locale      Locale
+	Resolutions map[int]string
+
+	// DateOrder says whether "05/01/2022" is in January or May. When empty
+	// the locale decides.
+	DateOrder DateOrder
+
+	// Now is the clock relative dates such as "yesterday" are read
+	// against. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// defaultParseOptions reads queries in the locale set by LOCALE and the
+// day and month order set by DATE_ORDER.
+func defaultParseOptions() ParseOptions {
+	return ParseOptions{Locale: userLocale(), DateOrder: dateOrder(), Now: time.Now}
+}
+
+// parseQueryWith parses a query with the given options.
+func parseQueryWith(input string, options ParseOptions) (Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return Query{}, err
+	}
+	if len(tokens) == 0 {
+		return Query{}, &ParseError{Pos: 0, Message: "empty query"}
+	}
+
+	for {
+		parser := &queryParser{input: input, tokens: tokens, query: Query{Intent: IntentConvert}, consumed: -1, options: options}
+		err = parser.parse()
+		if parser.err != nil {
+			return Query{}, parser.err
+		}
+
+		// A mistyped code is corrected and the query read again.
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) && parseErr.Correction != "" {
+			if _, done := options.Resolutions[parseErr.Pos]; !done {
+				options.Resolutions = maps.Clone(options.Resolutions)
+				if options.Resolutions == nil {
+					options.Resolutions = map[int]string{}
+				}
+				options.Resolutions[parseErr.Pos] = parseErr.Correction
+				continue
+			}
+		}
+		if err != nil {
+			return Query{}, err
+		}
+		return parser.query, nil
+	}
+}
+
+func (parser *queryParser) parse() error {
+	if err := parser.parseSource(); err != nil {
+		return err
+	}
+
+	links := parser.parseLinks()
+	for parser.query.Amount != nil && isListSeparator(links) && parser.atAmount() {
+		if err := parser.parseListedSource(); err != nil {
+			return err
+		}
+		links = parser.parseLinks()
+	}
+
+	if parser.query.Amount == nil && slices.ContainsFunc(links, isReverseLink) {
+		if parser.atAmount() {
+			parser.query.Intent = IntentReverse
+			if err := parser.parseAmount(); err != nil {
+				return err
+			}
+			links = nil
+		}
+	}
+
+	if err := parser.parseTargets(links); err != nil {
+		return err
+	}
+
+	if token, ok := parser.peek(); ok {
+		return &ParseError{Pos: token.Pos, Message: fmt.Sprintf("unexpected %q", token.Text)}
+	}
+	if len(parser.query.Chain) > 0 && (parser.query.Intent == IntentReverse || !parser.query.Period.isZero()) {
+		return &ParseError{Pos: 0, Message: "a chain of conversions needs an amount to convert on a single day"}
+	}
+	if len(parser.query.Sources) > 0 && !parser.query.Period.isZero() {
+		return &ParseError{Pos: 0, Message: "a range of dates takes a single amount"}
+	}
+	return nil
+}
+
+func (parser *queryParser) parseSource() error {
+	if parser.atAmount() {
+		if err := parser.parseAmount(); err != nil {
+			return err
+		}
+	}
+	parser.query.Unit = parser.parseUnit()
+
+	currency, ok := parser.parseCurrency()
+	if !ok {
+		// Filler comes before the source, as in "how much is", so the
+		// word nearest to where the currency belongs is the suspect.
+		return parser.expected("a currency", true)
+	}
+	parser.query.Source = currency
+
+	// "USD 100 to EUR" puts the amount after the code.
+	if parser.query.Amount == nil && parser.atAmount() {
+		return parser.parseAmount()
+	}
+	return nil
+}
+
+// parseListedSource reads another amount of a list such as "10 EUR, 20
+// GBP and 3000 JPY", keeping the first in Amount, Unit and Source.
+func (parser *queryParser) parseListedSource() error {
+	query := &parser.query
+	if len(query.Sources) == 0 {
+		query.Sources = []QuerySource{{Amount: query.Amount, Scale: query.Scale, Unit: query.Unit, Currency: query.Source}}
+	}
+
+	amount, scale, err := parser.readAmount()
+	if err != nil {
+		return err
+	}
+	source := QuerySource{Amount: amount, Scale: scale, Unit: parser.parseUnit()}
+	currency, ok := parser.parseCurrency()
+	if !ok {
+		return parser.expected("a currency", true)
+	}
+	source.Currency = currency
+	query.Sources = append(query.Sources, source)
+	return nil
+}
+
+// parseTargets reads the targets, and the currencies the result is
+// converted on to after each "then". leading holds the links read before
+// the first target.
+func (parser *queryParser) parseTargets(leading []Token) error {
+	parser.keepUnknownCodes(leading)
+
+	// listed is set after a list separator that did not end in an unknown
+	// code, so "to EUR and" is missing its last target.
+	chained, listed, first := false, false, true
+	for {
+		token, _ := parser.peek()
+		unit := parser.parseUnit()
+		currency, ok := parser.parseCurrency()
+		if !ok {
+			if len(parser.query.Targets) == 0 || unit != "" || chained || listed {
+				return parser.expected("a target currency", false)
+			}
+			return nil
+		}
+
+		switch {
+		case chained && unit != "":
+			return &ParseError{Pos: token.Pos, Message: "a chain of conversions cannot change units"}
+		case chained:
+			parser.query.Chain = append(parser.query.Chain, currency)
+		case len(parser.query.Chain) > 0:
+			return &ParseError{Pos: token.Pos, Message: `expected "then" before another currency in a chain`}
+		default:
+			if first {
+				parser.query.UnitTo = unit
+				first = false
+			}
+			if !slices.Contains(parser.query.Targets, currency) {
+				parser.query.Targets = append(parser.query.Targets, currency)
+			}
+		}
+
+		links := parser.parseLinks()
+		then := slices.IndexFunc(links, func(link Token) bool { return slices.Contains(chainWords, link.word()) })
+		chained = then >= 0
+
+		listed = isListSeparator(links) && !parser.keepUnknownCodes(links)
+		if chained && len(parser.query.Targets) > 1 {
+			return &ParseError{Pos: links[then].Pos, Message: "only a single target can be converted on"}
+		}
+	}
+}
+
+// keepUnknownCodes keeps the unknown codes passed over among links that
+// go on to a list separator, as "XYZ" in "to XYZ, EUR" or "EUR, XYZ and
+// GBP" is, so that each fails on its own and the other targets are still
+// converted. It reports whether there were any.
+func (parser *queryParser) keepUnknownCodes(links []Token) bool {
+	if len(parser.query.Chain) > 0 || !slices.ContainsFunc(links, isListLink) {
+		return false
+	}
+	codes := parser.unknownCodes(links[0].Pos)
+	for _, code := range codes {
+		if !slices.Contains(parser.query.Targets, code) {
+			parser.query.Targets = append(parser.query.Targets, code)
+		}
+	}
+	return len(codes) > 0
+}
+
+// unknownCodes lists the words after pos passed over as filler that are
+// written like currency codes, as "XYZ" is.
+func (parser *queryParser) unknownCodes(pos int) []string {
+	var codes []string
+	for _, skipped := range parser.skipped {
+		if skipped.Pos > pos && len(skipped.Text) == 3 && strings.IndexFunc(skipped.Text, func(char rune) bool { return char < 'A' || char > 'Z' }) < 0 {
+			codes = append(codes, skipped.Text)
+		}
+	}
+	return codes
+}
+
+// parseLinks consumes the connectors, list separators, the slash of a pair
+// such as "GBP/JPY", "then" and "for" or "get" between the parts of a
+// query, returning the tokens it consumed.
+func (parser *queryParser) parseLinks() []Token {
+	var links []Token
+	for {
+		token, ok := parser.peek()
+		if !ok || !(token.Kind == TokenComma || token.Kind == TokenSlash || isLinkWord(token.word())) {
+			return links
+		}
+		links = append(links, token)
+		parser.advance()
+	}
+}
+
+// isListSeparator reports whether links only separate the items of a
+// list, as the comma and "and" in "10 EUR, 20 GBP and 3000 JPY" do.
+func isListSeparator(links []Token) bool {
+	return len(links) > 0 && !slices.ContainsFunc(links, func(link Token) bool { return !isListLink(link) })
+}
+
+func isListLink(link Token) bool {
+	return link.Kind == TokenComma || slices.Contains(listWords, link.word())
+}
+
+func isReverseLink(link Token) bool {
+	return isReverseWord(link.word())
+}
+
+// atAmount reports whether the next token the grammar cares about starts
+// an amount, in digits or in words.
+func (parser *queryParser) atAmount() bool {
+	token, ok := parser.peek()
+	if !ok {
+		return false
+	}
+	if token.Kind == TokenNumber {
+		return true
+	}
+	_, _, ok = scanNumberWords(parser.tokens, parser.pos)
+	return ok
+}
+
+// parseAmount takes the amount read by readAmount into the query.
+func (parser *queryParser) parseAmount() error {
+	amount, scale, err := parser.readAmount()
+	if err != nil {
+		return err
+	}
+	parser.query.Amount, parser.query.Scale = amount, scale
+	return nil
+}
+
+// readAmount reads an amount in digits, optionally followed by a
+// magnitude as in "2.5k" or "3 million", or one written in words.
+func (parser *queryParser) readAmount() (*big.Rat, int, error) {
+	token, _ := parser.peek()
+	if token.Kind == TokenWord {
+		amount, length, _ := scanNumberWords(parser.tokens, parser.pos)
+		for range length {
+			parser.advance()
+		}
+		return amount, decimalScale(amount), nil
+	}
+
+	readings, err := readNumber(token.Text, parser.options.Locale.decimalMark())
+	if err != nil {
+		return nil, 0, &ParseError{Pos: token.Pos, Message: err.Error()}
+	}
+	if answer, ok := parser.options.Resolutions[token.Pos]; ok && slices.Contains(readings, answer) {
+		readings = []string{answer}
+	}
+	if len(readings) > 1 {
+		return nil, 0, &AmbiguityError{Kind: AmbiguousAmount, Pos: token.Pos, Text: token.Text, Candidates: readings}
+	}
+
+	amount, scale, err := parseDecimal(readings[0])
+	if err != nil {
+		return nil, 0, &ParseError{Pos: token.Pos, Message: fmt.Sprintf("invalid amount %q", token.Text)}
+	}
+	parser.advance()
+	if err := parser.checkNumberSuffix(token); err != nil {
+		return nil, 0, err
+	}
+
+	scaled := false
+	for {
+		magnitude, ok := digitMagnitude(parser.tokens, parser.pos)
+		if !ok {
+			break
+		}
+		amount.Mul(amount, big.NewRat(magnitude, 1))
+		scaled = true
+		parser.advance()
+	}
+	if scaled {
+		scale = decimalScale(amount)
+	}
+	return amount, scale, nil
+}
+
+// checkNumberSuffix rejects a word written straight after the digits of
+// number, such as the "e5" of "1e5", unless it is a magnitude, a unit or a
+// currency, rather than skipping it and answering for the wrong amount.
+func (parser *queryParser) checkNumberSuffix(number Token) error {
+	if parser.pos >= len(parser.tokens) {
+		return nil
+	}
+	next := parser.tokens[parser.pos]
+	if next.Kind != TokenWord || next.Pos != number.Pos+len([]rune(number.Text)) {
+		return nil
+	}
+
+	if _, ok := digitMagnitude(parser.tokens, parser.pos); ok {
+		return nil
+	}
+	if _, ok := parseMassUnit(next.word()); ok {
+		return nil
+	}
+	if _, ok := matchCurrency(parser.tokens, parser.pos); ok {
+		return nil
+	}
+	if _, ok := correction(next.Text, suggestCurrencies(next.Text)); ok {
+		return nil
+	}
+	return &ParseError{Pos: number.Pos, Message: fmt.Sprintf("malformed number %q", number.Text+next.Text)}
+}
+
+func (parser *queryParser) parseUnit() string {
+	token, ok := parser.peek()
+	if !ok || token.Kind != TokenWord {
+		return ""
+	}
+	unit, ok := parseMassUnit(token.word())
+	if ok {
+		parser.advance()
+	}
+	return unit
+}
+
+func (parser *queryParser) parseCurrency() (string, bool) {
+	if _, ok := parser.peek(); !ok {
+		return "", false
+	}
+	match, ok := parser.matchCurrency(parser.pos)
+	if !ok {
+		return "", false
+	}
+
+	if match.Kind != VocabularyCode || (isWordCode(match.Code) && match.Text != match.Code) {
+		parser.query.Interpretations = append(parser.query.Interpretations, match.interpretation())
+	}
+	for range match.Length {
+		parser.advance()
+	}
+	return match.Code, true
+}
+
+// matchCurrency finds the currency starting at pos. An earlier answer
+// settles it first, and also stands for a mistyped code. Codes and names
+// that are also English words are then settled from their context, and
+// symbols and names shared by several currencies by the locale's region.
+// Words that cannot be settled are an AmbiguityError.
+func (parser *queryParser) matchCurrency(pos int) (CurrencyMatch, bool) {
+	match, ok := matchCurrency(parser.tokens, pos)
+	if !ok {
+		token := parser.tokens[pos]
+		if code := parser.options.Resolutions[token.Pos]; code != "" && token.Kind == TokenWord {
+			return CurrencyMatch{Code: code, Candidates: []string{code}, Kind: VocabularyCorrection, Text: token.Text, Pos: token.Pos, Length: 1}, true
+		}
+		return match, false
+	}
+
+	if code, ok := parser.options.Resolutions[match.Pos]; ok {
+		match.Code = code
+		return match, code != ""
+	}
+
+	if isWordCurrency(match) {
+		currency, decided := readsAsCurrency(parser.tokens, pos)
+		if !decided {
+			parser.err = &AmbiguityError{Kind: AmbiguousCurrency, Pos: match.Pos, Text: match.Text, Candidates: match.Candidates, Optional: true}
+		}
+		return match, currency
+	}
+
+	if len(match.Candidates) > 1 {
+		preferred := parser.options.Locale.currency()
+		if !slices.Contains(match.Candidates, preferred) {
+			parser.err = &AmbiguityError{Kind: AmbiguousCurrency, Pos: match.Pos, Text: match.Text, Candidates: match.Candidates}
+		}
+		match.Code = preferred
+	}
+	return match, true
+}
+
+func (parser *queryParser) advance() {
+	parser.consumed = parser.tokens[parser.pos].Pos
+	parser.pos++
+}
+
+// peek returns the next token the grammar cares about. Filler words are
+// skipped, and dates, modifiers and "total" are taken into the query
+// wherever they appear.
+func (parser *queryParser) peek() (Token, bool) {
+	for parser.pos < len(parser.tokens) && parser.err == nil {
+		token := parser.tokens[parser.pos]
+		if match, ok, err := matchDateRange(parser.tokens, parser.pos, parser.now(), parser.dateOrder()); ok {
+			parser.err = parser.parseDateRange(match, err)
+			continue
+		}
+		if match, ok, err := matchDate(parser.tokens, parser.pos, parser.now(), parser.dateOrder()); ok {
+			parser.err = parser.parseDate(match, err)
+			continue
+		}
+		switch {
+		case token.Kind == TokenWord && modifierWords[token.word()] != "":
+			parser.err = parser.parseModifier(token)
+		case token.Kind == TokenWord && slices.Contains(totalWords, token.word()):
+			parser.query.Total = true
+			parser.pos++
+		case token.Kind == TokenWord && !parser.isSignificant(parser.pos):
+			parser.skipped = append(parser.skipped, token)
+			parser.pos++
+		default:
+			return token, true
+		}
+	}
+	return Token{Pos: len([]rune(parser.input))}, false
+}
+
+// parseDate takes a date expression into the query, checking that rates
+// can exist for the day it names.
+func (parser *queryParser) parseDate(match DateMatch, err error) error {
+	if err != nil {
+		return &ParseError{Pos: match.Pos, Message: err.Error()}
+	}
+	if parser.dated {
+		return &ParseError{Pos: match.Pos, Message: "only one date can be given"}
+	}
+
+	date, err := parser.resolveDate(match)
+	if err != nil {
+		return err
+	}
+	parser.dated = true
+	parser.pos += match.Length
+	if match.Latest {
+		return nil
+	}
+
+	parser.query.Date = date
+	if match.Text != date {
+		parser.query.Interpretations = append(parser.query.Interpretations, Interpretation{Text: match.Text, Date: date, Kind: VocabularyDate, Pos: match.Pos})
+	}
+	return nil
+}
+
+// parseDateRange takes a range of days into the query.
+func (parser *queryParser) parseDateRange(match RangeMatch, err error) error {
+	if err != nil {
+		return &ParseError{Pos: match.Pos, Message: err.Error()}
+	}
+	if parser.dated {
+		return &ParseError{Pos: match.Pos, Message: "only one date can be given"}
+	}
+
+	start, err := parser.resolveDate(match.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parser.resolveDate(match.End)
+	if err != nil {
+		return err
+	}
+	period := DateRange{Start: start, End: end}
+	if _, err := period.days(); err != nil {
+		return &ParseError{Pos: match.Pos, Message: err.Error()}
+	}
+
+	parser.dated = true
+	parser.pos += match.Length
+	parser.query.Period = period
+	if match.Relative {
+		parser.query.Interpretations = append(parser.query.Interpretations, Interpretation{Text: match.Text, Date: start + " to " + end, Kind: VocabularyDate, Pos: match.Pos})
+	}
+	return nil
+}
+
+// resolveDate settles the day a date expression names, asking which was
+// meant when day and month could be either way round, and checks that
+// rates can exist for it.
+func (parser *queryParser) resolveDate(match DateMatch) (string, error) {
+	if len(match.Readings) > 1 {
+		answer, ok := parser.options.Resolutions[match.Pos]
+		if !ok || !slices.Contains(match.Readings, answer) {
+			return "", &AmbiguityError{Kind: AmbiguousDate, Pos: match.Pos, Text: match.Text, Candidates: match.Readings}
+		}
+		match.Date, _ = time.Parse(time.DateOnly, answer)
+	}
+
+	if err := validateRateDate(match.Date, parser.now()); err != nil {
+		return "", &ParseError{Pos: match.Pos, Message: err.Error()}
+	}
+	return match.Date.Format(time.DateOnly), nil
+}
+
+func (parser *queryParser) now() time.Time {
+	if parser.options.Now == nil {
+		return time.Now()
+	}
+	return parser.options.Now()
+}
+
+func (parser *queryParser) dateOrder() DateOrder {
+	if parser.options.DateOrder != "" {
+		return parser.options.DateOrder
+	}
+	return parser.options.Locale.dateOrder()
+}
+
+func (parser *queryParser) parseModifier(token Token) error {
+	name := modifierWords[token.word()]
+	if parser.pos+1 >= len(parser.tokens) || parser.tokens[parser.pos+1].Kind != TokenWord {
+		return &ParseError{Pos: token.Pos, Message: fmt.Sprintf("%s needs a value", name)}
+	}
+
+	value := parser.tokens[parser.pos+1]
+	if name == "rounding" {
+		if _, err := parseRoundingMode(value.Text); err != nil {
+			return &ParseError{Pos: value.Pos, Message: err.Error()}
+		}
+	}
+	if parser.query.Modifiers == nil {
+		parser.query.Modifiers = make(map[string]string)
+	}
+	parser.query.Modifiers[name] = value.Text
+	parser.pos += 2
+	return nil
+}
+
+// expected reports what was missing. It points at a filler word from the
+// gap where the currency belonged, the nearest one when last is set and
+// otherwise the first, since an unrecognised code is the likeliest
+// mistake; failing that, at the next token or the end of the query.
+func (parser *queryParser) expected(what string, last bool) error {
+	token, ok := parser.peek()
+
+	var gap []Token
+	for _, skipped := range parser.skipped {
+		if skipped.Pos > parser.consumed && (!ok || skipped.Pos < token.Pos) {
+			gap = append(gap, skipped)
+		}
+	}
+	if len(gap) > 0 {
+		suspect := gap[0]
+		if last {
+			suspect = gap[len(gap)-1]
+		}
+		return misspelledCurrency(suspect, what)
+	}
+	if ok {
+		return &ParseError{Pos: token.Pos, Message: fmt.Sprintf("expected %s, found %q", what, token.Text)}
+	}
+	return &ParseError{Pos: token.Pos, Message: fmt.Sprintf("expected %s", what)}
+}
+
+// misspelledCurrency reports a word found where a currency belongs,
+// suggesting the codes it may be a typo of.
+func misspelledCurrency(token Token, what string) error {
+	if isReservedCode(token.Text) {
+		return &ParseError{Pos: token.Pos, Message: fmt.Sprintf("expected %s, found the reserved ISO 4217 code %q", what, token.Text)}
+	}
+	err := &ParseError{Pos: token.Pos, Message: fmt.Sprintf("expected %s, found %q", what, token.Text)}
+	suggestions := suggestCurrencies(token.Text)
+	if code, ok := correction(token.Text, suggestions); ok {
+		err.Correction = code
+	}
+	for _, suggestion := range suggestions {
+		err.Suggestions = append(err.Suggestions, suggestion.Code)
+	}
+	return err
+}
+
+// isSignificant reports whether the word at pos starts a currency, unit,
+// amount or link the grammar needs, rather than being filler.
+func (parser *queryParser) isSignificant(pos int) bool {
+	if _, ok := parser.matchCurrency(pos); ok {
+		return true
+	}
+	if _, _, ok := scanNumberWords(parser.tokens, pos); ok {
+		return true
+	}
+	word := parser.tokens[pos].word()
+	if _, ok := parseMassUnit(word); ok {
+		return true
+	}
+	return isLinkWord(word)
+}
+
+func isLinkWord(word string) bool {
+	return slices.Contains(connectorWords, word) || isReverseWord(word) || slices.Contains(listWords, word) || slices.Contains(chainWords, word)
+}
+
+func isReverseWord(word string) bool {
+	return slices.Contains(reverseWords, word)
+}
+
+// dataInput turns the query into the conversion it asks for, converting
+// one unit of the source when no amount was given.
+func (query Query) dataInput() DataInput {
+	input := DataInput{
+		Value:    Money{Amount: query.Amount, Scale: query.Scale, Currency: query.Source},
+		Unit:     query.Unit,
+		UnitTo:   query.UnitTo,
+		Targets:  query.Targets,
+		Date:     query.Date,
+		Rounding: Rounding{Mode: RoundingMode(query.Modifiers["rounding"])},
+		Profile:  query.Modifiers["profile"],
+		Overlay:  query.Modifiers["overlay"],
+	}
+	if len(query.Targets) > 0 {
+		input.CurrencyTo = query.Targets[0]
+	}
+
+	if query.Intent == IntentReverse {
+		input.Target = Money{Amount: query.Amount, Scale: query.Scale, Currency: input.CurrencyTo}
+		input.Value = Money{Currency: query.Source}
+	} else if input.Value.Amount == nil {
+		input.Value.Amount = big.NewRat(1, 1)
+	}
+
+	return input
+}
+
+// String spells the query out with currency codes, as in "100 USD to EUR,
+// GBP on 2023-06-30", showing how it was understood.
+func (query Query) String() string {
+	source := quantityLabel(query.Source, query.Unit)
+	targets := quantityLabel(strings.Join(query.Targets, ", "), query.UnitTo)
+
+	var text string
+	switch {
+	case query.Intent == IntentReverse:
+		text = fmt.Sprintf("%s for %s %s", source, Money{Amount: query.Amount, Scale: query.Scale}, targets)
+	case len(query.Sources) > 0:
+		sources := make([]string, len(query.Sources))
+		for i, source := range query.Sources {
+			sources[i] = fmt.Sprintf("%s %s", Money{Amount: source.Amount, Scale: source.Scale}, quantityLabel(source.Currency, source.Unit))
+		}
+		text = fmt.Sprintf("%s to %s", strings.Join(sources, ", "), targets)
+	case query.Amount != nil:
+		text = fmt.Sprintf("%s %s to %s", Money{Amount: query.Amount, Scale: query.Scale}, source, targets)
+	default:
+		text = fmt.Sprintf("%s to %s", source, targets)
+	}
+	for _, currency := range query.Chain {
+		text += " then " + currency
+	}
+
+	if query.Date != "" {
+		text += " on " + query.Date
+	}
+	if !query.Period.isZero() {
+		text += fmt.Sprintf(" from %s to %s", query.Period.Start, query.Period.End)
+	}
+	return text
+}
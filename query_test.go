@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func parseTestQuery(t *testing.T, input string) DataInput {
+	t.Helper()
+	query, err := parseQuery(input)
+	if err != nil {
+		t.Errorf("FAILED: parseQuery(%q) returned %v", input, err)
+	}
+	return query.dataInput()
+}
+
+func TestParseQuery(t *testing.T) {
+	query, err := parseQuery("How much is 1,250.50 usd in EUR and GBP on 2023-06-30 rounding half-even profile bank?")
+	if err != nil {
+		t.Fatalf("FAILED: parseQuery returned %v", err)
+	}
+
+	if query.Intent != IntentConvert || query.Amount.FloatString(2) != "1250.50" || query.Scale != 2 || query.Source != "USD" ||
+		!slices.Equal(query.Targets, []string{"EUR", "GBP"}) || query.Date != "2023-06-30" ||
+		query.Modifiers["rounding"] != "half-even" || query.Modifiers["profile"] != "bank" {
+		t.Errorf("FAILED: Unexpected query %+v", query)
+	}
+
+	dataInput := query.dataInput()
+	if dataInput.CurrencyTo != "EUR" || dataInput.Date != "2023-06-30" || dataInput.Rounding.Mode != RoundHalfEven || dataInput.Profile != "bank" {
+		t.Errorf("FAILED: Unexpected conversion %+v", dataInput)
+	}
+
+	query, err = parseQuery("how much USD do I need to get 2 oz gold")
+	if err != nil || query.Intent != IntentReverse || query.Source != "USD" || query.UnitTo != "ozt" || !slices.Equal(query.Targets, []string{"XAU"}) {
+		t.Errorf("FAILED: Expected a reverse query for 2 ozt XAU, got %+v (%v)", query, err)
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Position int
+		Message  string
+	}{
+		{"", 0, "empty query"},
+		{"100 XYZ to USD", 4, `expected a currency, found "XYZ"`},
+		{"What is 100 QQQ in USD?", 12, `expected a currency, found "QQQ"`},
+		{"100 USD to widgets please", 11, `expected a target currency, found "widgets"`},
+		{"100 USD to", 10, "expected a target currency"},
+		{"100 USD to EUR 200", 15, `unexpected "200"`},
+		{"100 USD to EUR on 2023-02-30", 18, `invalid date "2023-02-30"`},
+		{"100 USD to EUR rounding sideways", 24, `unknown rounding mode "sideways"`},
+		{"100 USD @ EUR", 8, `unexpected character '@'`},
+		{"1e5 USD to EUR", 0, `malformed number "1e5"`},
+		{"How much is 20x EUR in USD", 12, `malformed number "20x"`},
+	}
+
+	for _, testCase := range cases {
+		_, err := parseQuery(testCase.Input)
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Pos != testCase.Position || parseErr.Message != testCase.Message {
+			t.Errorf("FAILED: parseQuery(%q) Expected %q at %d, got %v", testCase.Input, testCase.Message, testCase.Position, err)
+		}
+	}
+}
+
+func TestParseQueryWordCodes(t *testing.T) {
+	cases := []struct {
+		Input   string
+		Source  string
+		Targets []string
+	}{
+		{"convert all 100 USD to EUR", "USD", []string{"EUR"}},
+		{"Try 100 USD in GBP", "USD", []string{"GBP"}},
+		{"100 USD to all", "USD", []string{"ALL"}},
+		{"50 cup in usd", "CUP", []string{"USD"}},
+		{"100 ALL to EUR", "ALL", []string{"EUR"}},
+		{"what is the top 10 EUR in USD", "EUR", []string{"USD"}},
+		{"How much is 20 GBP in TRY?", "GBP", []string{"TRY"}},
+	}
+
+	for _, testCase := range cases {
+		query, err := parseQuery(testCase.Input)
+		if err != nil {
+			t.Errorf("FAILED: parseQuery(%q) returned %v", testCase.Input, err)
+			continue
+		}
+		if query.Source != testCase.Source || !slices.Equal(query.Targets, testCase.Targets) {
+			t.Errorf("FAILED: %q read as %s to %v, expected %s to %v", testCase.Input, query.Source, query.Targets, testCase.Source, testCase.Targets)
+		}
+	}
+
+	_, err := parseQuery("100 all usd to eur")
+	var ambiguity *AmbiguityError
+	if !errors.As(err, &ambiguity) || ambiguity.Pos != 4 || !slices.Equal(ambiguity.Candidates, []string{"ALL"}) || !ambiguity.Optional {
+		t.Fatalf("FAILED: Expected \"all\" to be ambiguous, got %v", err)
+	}
+
+	query, err := parseQueryWith("100 all usd to eur", ParseOptions{Resolutions: map[int]string{4: ""}})
+	if err != nil || query.Source != "USD" || !slices.Equal(query.Targets, []string{"EUR"}) {
+		t.Errorf("FAILED: Expected \"all\" to be skipped once resolved, got %+v (%v)", query, err)
+	}
+	query, err = parseQueryWith("100 all usd to eur", ParseOptions{Resolutions: map[int]string{4: "ALL"}})
+	if err != nil || query.Source != "ALL" || !slices.Equal(query.Targets, []string{"USD", "EUR"}) {
+		t.Errorf("FAILED: Expected \"all\" to be the lek once resolved, got %+v (%v)", query, err)
+	}
+}
@@ -32,23 +32,17 @@ func initRedis() {
 	}
 }
 
-func getFromCache(key string) (Latest, error) {
+func getFromCache(key string) (CacheEnvelope, error) {
 	ctx := context.Background()
 	val, err := redisClient.Get(ctx, key).Result()
 	if err != nil {
-		return Latest{}, err
+		return CacheEnvelope{}, err
 	}
 
-	var latest Latest
-	err = json.Unmarshal([]byte(val), &latest)
-	if err != nil {
-		return Latest{}, err
-	}
-
-	return latest, nil
+	return decodeCacheEnvelope([]byte(val))
 }
 
-func setToCache(key string, value Latest, expiration time.Duration) error {
+func setToCache(key string, value CacheEnvelope, expiration time.Duration) error {
 	ctx := context.Background()
 	jsonValue, err := json.Marshal(value)
 	if err != nil {
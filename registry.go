@@ -0,0 +1,82 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"sort"
+)
+
+//go:embed currencies.json
+var currencyRegistryData []byte
+
+// Currency is an ISO 4217 entry. MinorUnits is nil for codes without a
+// meaningful fractional unit such as the precious metals and XDR.
+// Status is "active", "historic" for withdrawn codes, or "unofficial" for
+// widely quoted codes outside ISO 4217 such as BTC and CNH. CashIncrement is
+// the smallest amount payable in notes and coins, where it differs from the
+// minor unit. Introduced and Withdrawn bound when the currency was in use,
+// where that falls inside the period rates are available for.
+type Currency struct {
+	Code          string `json:"code"`
+	Numeric       string `json:"numeric,omitempty"`
+	MinorUnits    *int   `json:"minor_units"`
+	Name          string `json:"name"`
+	Symbol        string `json:"symbol,omitempty"`
+	Status        string `json:"status"`
+	CashIncrement string `json:"cash_increment,omitempty"`
+	Introduced    string `json:"introduced,omitempty"`
+	Withdrawn     string `json:"withdrawn,omitempty"`
+}
+
+var currencyRegistry = loadCurrencyRegistry(currencyRegistryData)
+
+func loadCurrencyRegistry(data []byte) map[string]Currency {
+	var currencies []Currency
+	if err := json.Unmarshal(data, &currencies); err != nil {
+		log.Fatalf("Failed to parse currency registry: %v", err)
+	}
+
+	registry := make(map[string]Currency, len(currencies))
+	for _, currency := range currencies {
+		registry[currency.Code] = currency
+	}
+	return registry
+}
+
+func lookupCurrency(code string) (Currency, bool) {
+	currency, ok := currencyRegistry[code]
+	return currency, ok
+}
+
+func listCurrencies() []Currency {
+	currencies := make([]Currency, 0, len(currencyRegistry))
+	for _, currency := range currencyRegistry {
+		currencies = append(currencies, currency)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+	return currencies
+}
+
+// currencyPrecision is the number of fractional digits amounts in code are
+// rounded and displayed to. Precious metals have metalPrecision, and other
+// codes without minor units fall back to the PRECISION setting.
+func currencyPrecision(code string) int {
+	if currency, ok := lookupCurrency(code); ok && currency.MinorUnits != nil {
+		return *currency.MinorUnits
+	}
+	if isPreciousMetal(code) {
+		return metalPrecision
+	}
+	if basket, ok := lookupBasket(code); ok && basket.MinorUnits != nil {
+		return *basket.MinorUnits
+	}
+	return int(getIntEnvVar("PRECISION"))
+}
+
+// displayMoney pads an amount to at least its currency's minor units, so
+// "100 USD" prints as "100.00" while "1.005 USD" keeps its last digit.
+func displayMoney(m Money) string {
+	m.Scale = max(m.Scale, currencyPrecision(m.Currency))
+	return m.String()
+}
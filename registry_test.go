@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestCurrencyPrecision(t *testing.T) {
+	expected := map[string]int{"USD": 2, "JPY": 0, "BHD": 3, "CLF": 4, "XAU": metalPrecision, "XDR": int(getIntEnvVar("PRECISION"))}
+
+	for code, places := range expected {
+		if precision := currencyPrecision(code); precision != places {
+			t.Errorf("FAILED: currencyPrecision(%s) Expected %d, got %d", code, places, precision)
+		}
+	}
+}
+
+func TestRegistryCoversCachedRates(t *testing.T) {
+	rates := castRateFromLatest(readFromCache())
+
+	for code := range rates {
+		if !checkValidCurrency(code) {
+			t.Errorf("FAILED: %s is quoted in the cache but missing from the registry", code)
+		}
+	}
+}
+
+func TestDisplayMoney(t *testing.T) {
+	cases := map[string]Money{
+		"100.00": newMoney(100, "USD"),
+		"100":    newMoney(100, "JPY"),
+		"5.000":  newMoney(5, "KWD"),
+	}
+
+	for expected, money := range cases {
+		if displayed := displayMoney(money); displayed != expected {
+			t.Errorf("FAILED: displayMoney(%v) Expected %s, got %s", money, expected, displayed)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+)
+
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half-up"
+	RoundHalfEven RoundingMode = "half-even"
+	RoundFloor    RoundingMode = "floor"
+	RoundCeiling  RoundingMode = "ceiling"
+	RoundDown     RoundingMode = "down"
+	RoundUp       RoundingMode = "up"
+	RoundCash     RoundingMode = "cash"
+)
+
+var roundingModes = []RoundingMode{RoundHalfUp, RoundHalfEven, RoundFloor, RoundCeiling, RoundDown, RoundUp, RoundCash}
+
+// Rounding is a mode together with the step amounts are rounded to, such
+// as 0.01 for two minor units or 0.05 for Swiss cash payments.
+type Rounding struct {
+	Mode      RoundingMode
+	Increment *big.Rat
+}
+
+func parseRoundingMode(input string) (RoundingMode, error) {
+	normalized := RoundingMode(strings.ReplaceAll(strings.ToLower(strings.TrimSpace(input)), "_", "-"))
+	for _, mode := range roundingModes {
+		if normalized == mode {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown rounding mode %q", input)
+}
+
+// resolveRounding picks the rounding for amounts in currency. An explicit
+// request wins, then the currency's entry in ROUNDING_DEFAULTS, then
+// ROUNDING_MODE, then half-up.
+func resolveRounding(currency string, requested string) (Rounding, error) {
+	if requested == "" {
+		return defaultRounding(currency), nil
+	}
+
+	mode, err := parseRoundingMode(requested)
+	if err != nil {
+		return Rounding{}, err
+	}
+	return roundingFor(currency, mode), nil
+}
+
+func defaultRounding(currency string) Rounding {
+	configured := getEnvVar("ROUNDING_MODE")
+
+	for _, entry := range strings.Split(getEnvVar("ROUNDING_DEFAULTS"), ",") {
+		code, mode, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if found && strings.EqualFold(code, currency) {
+			configured = mode
+		}
+	}
+
+	if configured == "" {
+		return roundingFor(currency, RoundHalfUp)
+	}
+
+	mode, err := parseRoundingMode(configured)
+	if err != nil {
+		log.Printf("Ignoring rounding default for %s: %v\n", currency, err)
+		mode = RoundHalfUp
+	}
+	return roundingFor(currency, mode)
+}
+
+// roundingFor sizes the increment for mode. Cash rounding uses the
+// currency's smallest note or coin and rounds half-up to it; currencies
+// without one round to their minor units.
+func roundingFor(currency string, mode RoundingMode) Rounding {
+	if mode == RoundCash {
+		if entry, ok := lookupCurrency(currency); ok && entry.CashIncrement != "" {
+			if increment, _, err := parseDecimal(entry.CashIncrement); err == nil {
+				return Rounding{Mode: mode, Increment: increment}
+			}
+		}
+	}
+
+	places := currencyPrecision(currency)
+	increment := new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil))
+	return Rounding{Mode: mode, Increment: increment}
+}
+
+func (rounding Rounding) places() int {
+	if rounding.Increment == nil {
+		return 0
+	}
+	return decimalPlaces(rounding.Increment)
+}
+
+func (rounding Rounding) String() string {
+	if rounding.Increment == nil {
+		return string(rounding.Mode)
+	}
+	return fmt.Sprintf("%s %s", rounding.Mode, rounding.Increment.FloatString(rounding.places()))
+}
+
+func (rounding Rounding) MarshalJSON() ([]byte, error) {
+	var increment string
+	if rounding.Increment != nil {
+		increment = rounding.Increment.FloatString(rounding.places())
+	}
+
+	return json.Marshal(struct {
+		Mode      RoundingMode `json:"mode"`
+		Increment string       `json:"increment,omitempty"`
+	}{rounding.Mode, increment})
+}
+
+// roundToIncrement rounds value to a whole multiple of increment.
+func roundToIncrement(value *big.Rat, increment *big.Rat, mode RoundingMode) *big.Rat {
+	steps := new(big.Rat).Quo(value, increment)
+	quotient, remainder := new(big.Int).QuoRem(steps.Num(), steps.Denom(), new(big.Int))
+
+	if remainder.Sign() != 0 {
+		negative := steps.Sign() < 0
+		awayFromZero := false
+
+		doubled := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2))
+		half := doubled.Cmp(steps.Denom())
+
+		switch mode {
+		case RoundHalfUp, RoundCash:
+			awayFromZero = half >= 0
+		case RoundHalfEven:
+			awayFromZero = half > 0 || (half == 0 && quotient.Bit(0) == 1)
+		case RoundFloor:
+			awayFromZero = negative
+		case RoundCeiling:
+			awayFromZero = !negative
+		case RoundUp:
+			awayFromZero = true
+		case RoundDown:
+			awayFromZero = false
+		}
+
+		if awayFromZero {
+			if negative {
+				quotient.Sub(quotient, big.NewInt(1))
+			} else {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		}
+	}
+
+	return new(big.Rat).Mul(new(big.Rat).SetInt(quotient), increment)
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestRoundToIncrement(t *testing.T) {
+	cases := []struct {
+		Input     string
+		Increment string
+		Mode      RoundingMode
+		Expected  string
+	}{
+		{"2.345", "0.01", RoundHalfUp, "2.35"},
+		{"2.345", "0.01", RoundHalfEven, "2.34"},
+		{"2.355", "0.01", RoundHalfEven, "2.36"},
+		{"-2.345", "0.01", RoundHalfEven, "-2.34"},
+		{"2.341", "0.01", RoundCeiling, "2.35"},
+		{"-2.349", "0.01", RoundCeiling, "-2.34"},
+		{"2.349", "0.01", RoundFloor, "2.34"},
+		{"-2.341", "0.01", RoundFloor, "-2.35"},
+		{"-2.349", "0.01", RoundDown, "-2.34"},
+		{"2.341", "0.01", RoundUp, "2.35"},
+		{"10.024", "0.05", RoundCash, "10"},
+		{"10.025", "0.05", RoundCash, "10.05"},
+		{"10.5", "1", RoundCash, "11"},
+	}
+
+	for _, testCase := range cases {
+		value, _, _ := parseDecimal(testCase.Input)
+		increment, _, _ := parseDecimal(testCase.Increment)
+		expected, _, _ := parseDecimal(testCase.Expected)
+
+		if rounded := roundToIncrement(value, increment, testCase.Mode); rounded.Cmp(expected) != 0 {
+			t.Errorf("FAILED: roundToIncrement(%s, %s, %s) Expected %s, got %s", testCase.Input, testCase.Increment, testCase.Mode, testCase.Expected, rounded.FloatString(4))
+		}
+	}
+}
+
+func TestResolveRounding(t *testing.T) {
+	rounding, err := resolveRounding("CHF", "cash")
+	if err != nil || rounding.String() != "cash 0.05" {
+		t.Errorf("FAILED: resolveRounding(CHF, cash) Expected cash 0.05, got %v (%v)", rounding, err)
+	}
+
+	rounding, err = resolveRounding("JPY", "half_even")
+	if err != nil || rounding.String() != "half-even 1" {
+		t.Errorf("FAILED: resolveRounding(JPY, half_even) Expected half-even 1, got %v (%v)", rounding, err)
+	}
+
+	if _, err := resolveRounding("USD", "sideways"); err == nil {
+		t.Errorf("FAILED: resolveRounding(USD, sideways) Expected an error")
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// maxSeriesDays bounds a range query, as every day is a separate request
+// to the provider.
+const maxSeriesDays = 366
+
+// DateRange is the span of days a range query asks for, both inclusive.
+type DateRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+func (period DateRange) isZero() bool {
+	return period.Start == "" && period.End == ""
+}
+
+// days lists every day of the range in order.
+func (period DateRange) days() ([]string, error) {
+	start, err := time.Parse(time.DateOnly, period.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q", period.Start)
+	}
+	end, err := time.Parse(time.DateOnly, period.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %q", period.End)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("the range ends on %s, before it starts on %s", period.End, period.Start)
+	}
+	if count := int(end.Sub(start).Hours()/24) + 1; count > maxSeriesDays {
+		return nil, fmt.Errorf("ranges are limited to %d days, not %d", maxSeriesDays, count)
+	}
+
+	var days []string
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		days = append(days, day.Format(time.DateOnly))
+	}
+	return days, nil
+}
+
+// SeriesPoint is one day of a rate series: the rate and the amount asked
+// about converted at it.
+type SeriesPoint struct {
+	Date  string `json:"date"`
+	Rate  string `json:"rate"`
+	Value Money  `json:"value"`
+
+	rate *big.Rat
+}
+
+// SeriesSummary describes how the rate moved over the range. Change is the
+// last rate less the first, and ChangePercent that change relative to the
+// first.
+type SeriesSummary struct {
+	StartRate     string `json:"start_rate"`
+	EndRate       string `json:"end_rate"`
+	Change        string `json:"change"`
+	ChangePercent string `json:"change_percent"`
+	Min           string `json:"min"`
+	MinDate       string `json:"min_date"`
+	Max           string `json:"max"`
+	MaxDate       string `json:"max_date"`
+	Average       string `json:"average"`
+}
+
+// RateSeries is the daily rate from one currency to another over a range.
+// Missing lists the days the provider had no rates for.
+type RateSeries struct {
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Amount  string        `json:"amount"`
+	Start   string        `json:"start"`
+	End     string        `json:"end"`
+	Points  []SeriesPoint `json:"points"`
+	Summary SeriesSummary `json:"summary"`
+	Missing []string      `json:"missing,omitempty"`
+}
+
+// rateSeries prices dataInput on every day of period, giving a series for
+// each of its targets. fetch gets each day's rates once for all of them;
+// a day it has no rates for is listed as missing, and any other error
+// stops the series.
+func rateSeries(dataInput DataInput, period DateRange, fetch func(date string) (RateSnapshot, error)) ([]RateSeries, error) {
+	days, err := period.days()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]RateSeries, len(dataInput.Targets))
+	roundings := make([]Rounding, len(dataInput.Targets))
+	for i, target := range dataInput.Targets {
+		if roundings[i], err = resolveRounding(target, string(dataInput.Rounding.Mode)); err != nil {
+			return nil, err
+		}
+		results[i] = RateSeries{
+			From:   dataInput.Value.Currency,
+			To:     target,
+			Amount: dataInput.Value.String(),
+			Start:  period.Start,
+			End:    period.End,
+		}
+	}
+
+	var missing []string
+	for _, day := range days {
+		snapshot, err := fetch(day)
+		if errors.Is(err, errNoRatesForDay) {
+			missing = append(missing, day)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i, target := range dataInput.Targets {
+			dated := dataInput.withTarget(target)
+			dated.Date = day
+			rate, _, err := conversionRate(dated, snapshot)
+			if err != nil {
+				return nil, err
+			}
+			value, err := convertAtRate(dated.Value, target, rate, roundings[i])
+			if err != nil {
+				return nil, err
+			}
+			results[i].Points = append(results[i].Points, SeriesPoint{Date: day, Rate: formatRate(rate), Value: value, rate: rate})
+		}
+	}
+
+	if len(missing) == len(days) {
+		return nil, fmt.Errorf("no rates found between %s and %s", period.Start, period.End)
+	}
+	for i := range results {
+		results[i].Missing = missing
+		results[i].Summary = summarizeSeries(results[i].Points)
+	}
+	return results, nil
+}
+
+func summarizeSeries(points []SeriesPoint) SeriesSummary {
+	first, last := points[0], points[len(points)-1]
+	lowest, highest := first, first
+	total := new(big.Rat)
+	for _, point := range points {
+		if point.rate.Cmp(lowest.rate) < 0 {
+			lowest = point
+		}
+		if point.rate.Cmp(highest.rate) > 0 {
+			highest = point
+		}
+		total.Add(total, point.rate)
+	}
+
+	change := new(big.Rat).Sub(last.rate, first.rate)
+	percent := new(big.Rat)
+	if first.rate.Sign() != 0 {
+		percent.Quo(change, first.rate)
+		percent.Mul(percent, big.NewRat(100, 1))
+	}
+
+	return SeriesSummary{
+		StartRate:     first.Rate,
+		EndRate:       last.Rate,
+		Change:        formatRate(change),
+		ChangePercent: percent.FloatString(2),
+		Min:           lowest.Rate,
+		MinDate:       lowest.Date,
+		Max:           highest.Rate,
+		MaxDate:       highest.Date,
+		Average:       formatRate(total.Quo(total, big.NewRat(int64(len(points)), 1))),
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRateSeries(t *testing.T) {
+	rates := map[string]float64{
+		"2023-01-01": 0.90,
+		"2023-01-02": 0.95,
+		"2023-01-04": 0.85,
+		"2023-01-05": 0.99,
+	}
+	fetches := 0
+	fetch := func(date string) (RateSnapshot, error) {
+		fetches++
+		rate, ok := rates[date]
+		if !ok {
+			return RateSnapshot{}, errNoRatesForDay
+		}
+		return newRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": rate, "GBP": rate / 2}), nil
+	}
+
+	input := parseTestQuery(t, "100 USD to EUR and GBP")
+	results, err := rateSeries(input, DateRange{Start: "2023-01-01", End: "2023-01-05"}, fetch)
+	if err != nil {
+		t.Fatalf("FAILED: rateSeries returned %v", err)
+	}
+	if len(results) != 2 || results[1].To != "GBP" || fetches != 5 {
+		t.Fatalf("FAILED: Expected a series for EUR and GBP from 5 fetches, got %d series from %d fetches", len(results), fetches)
+	}
+	series := results[0]
+
+	if len(series.Points) != 4 || !slices.Equal(series.Missing, []string{"2023-01-03"}) {
+		t.Errorf("FAILED: Expected 4 points and 2023-01-03 missing, got %d points and %v", len(series.Points), series.Missing)
+	}
+	if value := series.Points[1].Value.String(); value != "95.00" {
+		t.Errorf("FAILED: Expected 100 USD to be 95.00 EUR on 2023-01-02, got %s", value)
+	}
+
+	expected := SeriesSummary{
+		StartRate: "0.9", EndRate: "0.99", Change: "0.09", ChangePercent: "10.00",
+		Min: "0.85", MinDate: "2023-01-04", Max: "0.99", MaxDate: "2023-01-05", Average: "0.9225",
+	}
+	if series.Summary != expected {
+		t.Errorf("FAILED: Expected summary %+v, got %+v", expected, series.Summary)
+	}
+
+	refused := func(date string) (RateSnapshot, error) {
+		return RateSnapshot{}, errors.New("rates request refused with status 401")
+	}
+	if _, err := rateSeries(input, DateRange{Start: "2023-01-01", End: "2023-01-05"}, refused); err == nil {
+		t.Errorf("FAILED: Expected a refused request to fail the series rather than count as missing")
+	}
+	if _, err := rateSeries(input, DateRange{Start: "2023-01-05", End: "2023-01-01"}, fetch); err == nil {
+		t.Errorf("FAILED: Expected a range ending before it starts to fail")
+	}
+	if _, err := rateSeries(input, DateRange{Start: "2020-01-01", End: "2023-01-01"}, fetch); err == nil {
+		t.Errorf("FAILED: Expected a range over %d days to fail", maxSeriesDays)
+	}
+}
+
+func TestParseQueryRanges(t *testing.T) {
+	now := time.Date(2023, time.July, 12, 0, 0, 0, 0, time.UTC)
+	options := ParseOptions{Locale: parseLocale("en-GB"), Now: func() time.Time { return now }}
+
+	cases := []struct {
+		Input   string
+		Source  string
+		Targets []string
+		Period  DateRange
+	}{
+		{"EUR to USD from 2023-01-01 to 2023-03-31", "EUR", []string{"USD"}, DateRange{"2023-01-01", "2023-03-31"}},
+		{"GBP/JPY over the last 30 days", "GBP", []string{"JPY"}, DateRange{"2023-06-13", "2023-07-12"}},
+		{"EUR to USD last month", "EUR", []string{"USD"}, DateRange{"2023-06-01", "2023-06-30"}},
+		{"EUR to USD last week", "EUR", []string{"USD"}, DateRange{"2023-07-03", "2023-07-09"}},
+		{"EUR to USD last quarter", "EUR", []string{"USD"}, DateRange{"2023-04-01", "2023-06-30"}},
+		{"EUR to USD last year", "EUR", []string{"USD"}, DateRange{"2022-01-01", "2022-12-31"}},
+		{"USD in EUR and GBP between Jan 1 2023 and Jan 31 2023", "USD", []string{"EUR", "GBP"}, DateRange{"2023-01-01", "2023-01-31"}},
+		{"100 CHF to EUR past three months", "CHF", []string{"EUR"}, DateRange{"2023-04-13", "2023-07-12"}},
+		{"EUR/USD 01/02/2023 until yesterday", "EUR", []string{"USD"}, DateRange{"2023-02-01", "2023-07-11"}},
+		{"100 USD to EUR from jan 1 to jan 5 2023", "USD", []string{"EUR"}, DateRange{"2023-01-01", "2023-01-05"}},
+		{"EUR to USD from Dec 20 to Jan 5 2023", "EUR", []string{"USD"}, DateRange{"2022-12-20", "2023-01-05"}},
+		{"EUR to USD from Dec 20 2022 to Jan 5", "EUR", []string{"USD"}, DateRange{"2022-12-20", "2023-01-05"}},
+	}
+
+	for _, testCase := range cases {
+		query, err := parseQueryWith(testCase.Input, options)
+		if err != nil {
+			t.Errorf("FAILED: parseQueryWith(%q) returned %v", testCase.Input, err)
+			continue
+		}
+		if query.Source != testCase.Source || !slices.Equal(query.Targets, testCase.Targets) || query.Period != testCase.Period || query.Date != "" {
+			t.Errorf("FAILED: %q read as %s to %v over %+v, expected %s to %v over %+v", testCase.Input, query.Source, query.Targets, query.Period, testCase.Source, testCase.Targets, testCase.Period)
+		}
+	}
+
+	// "the last 30 days" spans exactly 30 days, today included.
+	query, err := parseQueryWith("GBP/JPY over the last 30 days", options)
+	if days, _ := query.Period.days(); err != nil || len(days) != 30 {
+		t.Errorf("FAILED: Expected the last 30 days to span 30 days, got %d (%v)", len(days), err)
+	}
+
+	for _, input := range []string{"EUR to USD from 2023-03-31 to 2023-01-01", "EUR to USD from 2023-01-01 to 2023-03-31 on 2023-02-01"} {
+		var parseErr *ParseError
+		if _, err := parseQueryWith(input, options); !errors.As(err, &parseErr) {
+			t.Errorf("FAILED: Expected parseQueryWith(%q) to fail, got %v", input, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Cache layers a RateSnapshot can be served from.
+const (
+	CacheLayerFile  = "file"
+	CacheLayerRedis = "redis"
+	CacheLayerAPI   = "api"
+)
+
+// RateSnapshot is a rate table together with where it came from. Rates
+// are units of each currency per unit of Base; Pairs holds any direct
+// quotes the provider published, keyed "FROM/TO", and Quotes the pairs
+// from exchanges or desks that are routed through when neither can price a
+// conversion. Timestamp is when the provider published the rates and
+// FetchedAt when they were downloaded. Stale is set when an expired cache
+// had to be used.
+type RateSnapshot struct {
+	Rates     map[string]float64
+	Pairs     map[string]float64
+	Quotes    []PairQuote
+	Base      string
+	Provider  string
+	Timestamp int64
+	FetchedAt int64
+	Layer     string
+	Stale     bool
+}
+
+// Provenance tells a reader exactly which rate produced a result. When an
+// overlay applied, Rate is the overlay's rate rather than the market's.
+type Provenance struct {
+	Rate           string    `json:"rate"`
+	InverseRate    string    `json:"inverse_rate"`
+	MidRate        string    `json:"mid_rate,omitempty"`
+	RatesTimestamp time.Time `json:"rates_timestamp"`
+	Provider       string    `json:"provider"`
+	Base           string    `json:"base"`
+	CacheLayer     string    `json:"cache_layer"`
+	Path           []string  `json:"path"`
+	Stale          bool      `json:"stale"`
+}
+
+func newRateSnapshot(base string, rates map[string]float64) RateSnapshot {
+	return RateSnapshot{Rates: rates, Base: base}
+}
+
+func snapshotFromEnvelope(envelope CacheEnvelope, layer string) RateSnapshot {
+	return RateSnapshot{
+		Rates:     castRateFromLatest(envelope.latest()),
+		Pairs:     envelope.Pairs,
+		Base:      envelope.Base,
+		Provider:  envelope.Provider,
+		Timestamp: envelope.Timestamp,
+		FetchedAt: envelope.FetchedAt,
+		Layer:     layer,
+	}
+}
+
+// provenance describes the rate dataInput converts at and where it came
+// from. Rate is the rate applied, after any overlay and the spread of
+// dataInput.Profile, and MidRate the market's mid rate, which is left out
+// when only an overlay prices the pair.
+func (snapshot RateSnapshot) provenance(dataInput DataInput) (Provenance, error) {
+	quote, err := priceConversion(dataInput, snapshot)
+	if err != nil {
+		return Provenance{}, err
+	}
+	_, source, err := conversionRate(dataInput, snapshot)
+	if err != nil {
+		return Provenance{}, err
+	}
+
+	provenance := Provenance{
+		Rate:           formatRate(quote.EffectiveRate),
+		RatesTimestamp: time.Unix(snapshot.Timestamp, 0).UTC(),
+		Provider:       snapshot.Provider,
+		Base:           snapshot.Base,
+		CacheLayer:     snapshot.Layer,
+		Path:           source.Path,
+		Stale:          snapshot.Stale,
+	}
+	if quote.EffectiveRate.Sign() != 0 {
+		provenance.InverseRate = formatRate(new(big.Rat).Inv(quote.EffectiveRate))
+	}
+
+	market := dataInput
+	market.Overlay = ""
+	if quote.Overlay == "" {
+		provenance.MidRate = formatRate(quote.MidRate)
+	} else if midRate, _, err := conversionRate(market, snapshot); err == nil {
+		provenance.MidRate = formatRate(midRate)
+	}
+	return provenance, nil
+}
+
+// base is the currency the rate table is quoted against. Snapshots that do
+// not say are from the USD-based default provider.
+func (snapshot RateSnapshot) base() string {
+	if snapshot.Base == "" {
+		return "USD"
+	}
+	return snapshot.Base
+}
+
+// rateGraph joins the rate table, the provider's direct pairs and any
+// quoted pairs into one graph, so assets that only trade against each
+// other can still reach the table.
+func (snapshot RateSnapshot) rateGraph() *RateGraph {
+	graph := newRateGraph()
+	base := snapshot.base()
+	for currency := range snapshot.Rates {
+		if currency != base {
+			graph.addMid(base, currency, snapshot.tableRate(currency))
+		}
+	}
+	for pair, rate := range snapshot.Pairs {
+		if from, to, ok := strings.Cut(pair, "/"); ok {
+			graph.addMid(from, to, rateToRat(rate))
+		}
+	}
+	for _, quote := range snapshot.Quotes {
+		graph.addQuote(quote)
+	}
+	return graph
+}
+
+// quote is the provider's own price of one from in to: a direct pair quote
+// or its inverse, or a rate table entry when either side is the base.
+func (snapshot RateSnapshot) quote(from string, to string) (*big.Rat, bool) {
+	if rate := rateToRat(snapshot.Pairs[from+"/"+to]); rate.Sign() > 0 {
+		return rate, true
+	}
+	if rate := rateToRat(snapshot.Pairs[to+"/"+from]); rate.Sign() > 0 {
+		return rate.Inv(rate), true
+	}
+
+	base := snapshot.base()
+	switch {
+	case from == base:
+		rate := snapshot.tableRate(to)
+		return rate, rate.Sign() > 0
+	case to == base:
+		rate := snapshot.tableRate(from)
+		if rate.Sign() <= 0 {
+			return rate, false
+		}
+		return rate.Inv(rate), true
+	}
+	return nil, false
+}
+
+func (snapshot RateSnapshot) tableRate(currency string) *big.Rat {
+	if currency == snapshot.base() {
+		return big.NewRat(1, 1)
+	}
+	return rateToRat(snapshot.Rates[currency])
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSnapshotProvenance(t *testing.T) {
+	snapshot := RateSnapshot{
+		Rates:     map[string]float64{"USD": 1, "EUR": 0.8, "GBP": 0.5},
+		Base:      "USD",
+		Provider:  defaultProvider,
+		Timestamp: 1688169597,
+		Layer:     CacheLayerRedis,
+	}
+
+	provenance, err := snapshot.provenance(DataInput{Value: newMoney(1, "EUR"), CurrencyTo: "GBP"})
+	if err != nil || provenance.Rate != "0.625" || provenance.InverseRate != "1.6" || provenance.MidRate != "0.625" {
+		t.Errorf("FAILED: Expected rate and mid rate 0.625 and inverse 1.6, got %+v (%v)", provenance, err)
+	}
+
+	// With a profile the rate applied is the mid rate less the spread.
+	provenance, err = snapshot.provenance(DataInput{Value: newMoney(100, "EUR"), CurrencyTo: "GBP", Profile: "bank"})
+	if err != nil || provenance.Rate != "0.609375" || provenance.MidRate != "0.625" {
+		t.Errorf("FAILED: Expected rate 0.609375 from mid rate 0.625, got %+v (%v)", provenance, err)
+	}
+
+	if _, err := snapshot.provenance(DataInput{Value: newMoney(1, "EUR"), CurrencyTo: "JPY"}); !errors.Is(err, ErrMissingRate) {
+		t.Errorf("FAILED: Expected %v for JPY, got %v", ErrMissingRate, err)
+	}
+	if !provenance.RatesTimestamp.Equal(time.Unix(1688169597, 0)) || provenance.CacheLayer != CacheLayerRedis || provenance.Stale {
+		t.Errorf("FAILED: Unexpected provenance %+v", provenance)
+	}
+}
+
+func TestExpiredFileCacheIsStale(t *testing.T) {
+	snapshot, err := useCache(time.Now().Unix(), true)
+	if err != nil {
+		t.Fatalf("FAILED: useCache returned %v", err)
+	}
+
+	if snapshot.Layer != CacheLayerFile || !snapshot.Stale || snapshot.Base != "USD" || len(snapshot.Rates) == 0 {
+		t.Errorf("FAILED: Expected a stale USD snapshot from the file cache, got %s/%v/%s", snapshot.Layer, snapshot.Stale, snapshot.Base)
+	}
+}
+
+func TestExchangeRatePaths(t *testing.T) {
+	snapshot := newRateSnapshot("EUR", map[string]float64{"USD": 1.1, "GBP": 0.85, "JPY": 160})
+	snapshot.Pairs = map[string]float64{"GBP/USD": 1.3, "USD/JPY": 150}
+
+	cases := []struct {
+		From     string
+		To       string
+		Pivot    string
+		Expected *big.Rat
+		Path     []string
+	}{
+		{"USD", "GBP", "", big.NewRat(10, 13), []string{"USD", "GBP"}},
+		{"EUR", "JPY", "", big.NewRat(160, 1), []string{"EUR", "JPY"}},
+		{"JPY", "USD", "", big.NewRat(1, 150), []string{"JPY", "USD"}},
+		{"GBP", "JPY", "", big.NewRat(3200, 17), []string{"GBP", "EUR", "JPY"}},
+		{"GBP", "JPY", "USD", big.NewRat(195, 1), []string{"GBP", "USD", "JPY"}},
+	}
+
+	for _, testCase := range cases {
+		t.Setenv("PIVOT_CURRENCY", testCase.Pivot)
+		rate, path, err := exchangeRate(testCase.From, testCase.To, snapshot)
+		if err != nil || rate.Cmp(testCase.Expected) != 0 || !slices.Equal(path, testCase.Path) {
+			t.Errorf("FAILED: exchangeRate(%s, %s) via %q Expected %s along %v, got %v along %v (%v)", testCase.From, testCase.To, testCase.Pivot, testCase.Expected, testCase.Path, rate, path, err)
+		}
+	}
+}
+
+func TestConvertFromEuroBasedRates(t *testing.T) {
+	snapshot := newRateSnapshot("EUR", map[string]float64{"USD": 1.25, "GBP": 0.8})
+
+	result, err := convert(DataInput{Value: newMoney(100, "USD"), CurrencyTo: "GBP"}, snapshot)
+	if err != nil || result.String() != "64.00" {
+		t.Errorf("FAILED: convert Expected 64.00 GBP, got %s (%v)", result, err)
+	}
+
+	provenance, err := snapshot.provenance(DataInput{Value: newMoney(100, "USD"), CurrencyTo: "GBP"})
+	if err != nil || !slices.Equal(provenance.Path, []string{"USD", "EUR", "GBP"}) {
+		t.Errorf("FAILED: Expected the path USD, EUR, GBP, got %v (%v)", provenance.Path, err)
+	}
+
+	_, err = convert(DataInput{Value: newMoney(100, "USD"), CurrencyTo: "JPY"}, snapshot)
+	if !errors.Is(err, ErrMissingRate) {
+		t.Errorf("FAILED: Expected %v for JPY, got %v", ErrMissingRate, err)
+	}
+}
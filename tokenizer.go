@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Dates written in digits: ISO 8601, year first with slashes, and day and
+// month in either order with slashes or dots, as in "05/01/2022".
+var (
+	isoDatePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	yearFirstPattern   = regexp.MustCompile(`^(\d{4})/(\d{1,2})/(\d{1,2})$`)
+	numericDatePattern = regexp.MustCompile(`^(\d{1,2})[/.](\d{1,2})[/.](\d{4}|\d{2})$`)
+)
+
+// ignoredPunctuation is dropped between tokens, as it carries nothing a
+// conversion query needs.
+const ignoredPunctuation = "?!.;:'\"()"
+
+type TokenKind int
+
+const (
+	TokenWord TokenKind = iota
+	TokenNumber
+	TokenDate
+	TokenComma
+	TokenSlash
+	TokenSymbol
+)
+
+// Token is one lexical unit of a query. Pos is the offset of its first
+// character, counted in characters from the start of the query.
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+}
+
+// word is the token as matched against currency codes and keywords.
+func (token Token) word() string {
+	return strings.ToUpper(token.Text)
+}
+
+// ParseError points at the character of a query that could not be
+// understood. Suggestions are the currencies a mistyped word there may
+// have meant, and Correction the one it almost certainly did.
+type ParseError struct {
+	Pos         int
+	Message     string
+	Suggestions []string
+	Correction  string
+}
+
+func (err *ParseError) Error() string {
+	if len(err.Suggestions) > 0 {
+		return fmt.Sprintf("%s at column %d; did you mean %s?", err.Message, err.Pos+1, joinAlternatives(err.Suggestions))
+	}
+	return fmt.Sprintf("%s at column %d", err.Message, err.Pos+1)
+}
+
+// tokenize splits a query into words, numbers, dates, currency symbols
+// and the commas and slashes that separate lists and pairs. A number
+// written against a word or symbol, as in "100JPY" or "$100", is two
+// tokens, while letters written against a symbol, as in "US$" or "R$", are
+// one.
+func tokenize(input string) ([]Token, error) {
+	var tokens []Token
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		char := runes[i]
+		switch {
+		case unicode.IsSpace(char) || strings.ContainsRune(ignoredPunctuation, char):
+			i++
+		case startsNumber(runes, i):
+			end := scanNumber(runes, i)
+			token := Token{Kind: TokenNumber, Text: string(runes[i:end]), Pos: i}
+			if isoDatePattern.MatchString(token.Text) || yearFirstPattern.MatchString(token.Text) || numericDatePattern.MatchString(token.Text) {
+				token.Kind = TokenDate
+			} else if strings.ContainsAny(token.Text[1:], "-/") {
+				return nil, &ParseError{Pos: i, Message: fmt.Sprintf("malformed number %q", token.Text)}
+			}
+			tokens = append(tokens, token)
+			i = end
+		case unicode.IsLetter(char):
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '-' || runes[end] == '\'') {
+				end++
+			}
+			if end < len(runes) && unicode.Is(unicode.Sc, runes[end]) {
+				tokens = append(tokens, Token{Kind: TokenSymbol, Text: string(runes[i : end+1]), Pos: i})
+				i = end + 1
+				continue
+			}
+			text := strings.TrimRight(string(runes[i:end]), "-'")
+			tokens = append(tokens, Token{Kind: TokenWord, Text: text, Pos: i})
+			i = end
+		case unicode.Is(unicode.Sc, char):
+			tokens = append(tokens, Token{Kind: TokenSymbol, Text: string(char), Pos: i})
+			i++
+		case char == ',':
+			tokens = append(tokens, Token{Kind: TokenComma, Text: ",", Pos: i})
+			i++
+		case char == '/':
+			tokens = append(tokens, Token{Kind: TokenSlash, Text: "/", Pos: i})
+			i++
+		default:
+			return nil, &ParseError{Pos: i, Message: fmt.Sprintf("unexpected character %q", char)}
+		}
+	}
+
+	return tokens, nil
+}
+
+func startsNumber(runes []rune, i int) bool {
+	if unicode.IsDigit(runes[i]) {
+		return true
+	}
+	return (runes[i] == '-' || runes[i] == '.') && i+1 < len(runes) && unicode.IsDigit(runes[i+1])
+}
+
+// groupingSpaces separate thousands in French and other locales, as in
+// "1 234,56".
+const groupingSpaces = " \u00a0\u202f"
+
+// scanNumber finds the end of the number starting at i. Separators only
+// count when a digit follows, so the comma in "5 EUR, 10 USD" and a full
+// stop ending a sentence are left alone. Apostrophes group digits as in
+// "1'234.50", and so do spaces before a group of exactly three digits in a
+// number of plain digit groups, as in "1 234 567".
+func scanNumber(runes []rune, i int) int {
+	end := i + 1
+	group := end - i
+	for end < len(runes) {
+		char := runes[end]
+		if unicode.IsDigit(char) {
+			end++
+			group++
+			continue
+		}
+		followedByDigit := end+1 < len(runes) && unicode.IsDigit(runes[end+1])
+		switch {
+		case strings.ContainsRune(".,-/'’", char) && followedByDigit:
+			end++
+			group = 0
+			continue
+		case strings.ContainsRune(groupingSpaces, char) && group <= 3 && digitGroupAt(runes, end+1) && !strings.ContainsAny(string(runes[i:end]), ".,-/'’"):
+			end++
+			group = 0
+			continue
+		}
+		break
+	}
+	return end
+}
+
+// digitGroupAt reports whether exactly three digits start at i.
+func digitGroupAt(runes []rune, i int) bool {
+	count := 0
+	for i+count < len(runes) && unicode.IsDigit(runes[i+count]) {
+		count++
+	}
+	return count == 3
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+//go:embed vocabulary.json
+var vocabularyData []byte
+
+// maxPhraseWords is the longest currency name matched, as in "new zealand
+// dollars".
+const maxPhraseWords = 3
+
+// Kinds of words a currency can be written as, the kind of a mistyped
+// code that was corrected, and the kind of a date written in words.
+const (
+	VocabularyCode       = "code"
+	VocabularySymbol     = "symbol"
+	VocabularyName       = "name"
+	VocabularySlang      = "slang"
+	VocabularyMetal      = "metal"
+	VocabularyCorrection = "correction"
+	VocabularyDate       = "date"
+)
+
+// registryNameStopwords are ISO 4217 names that are everyday English words,
+// which would otherwise turn "I won" or "som" into currencies. Their
+// qualified forms, such as "korean won", are in vocabulary.json, as is a
+// bare "won", which wordNames leaves to the words around it.
+var registryNameStopwords = []string{"won", "sol", "som"}
+
+// irregularPlurals maps plurals that dropping an "s" does not undo.
+var irregularPlurals = map[string]string{
+	"reais":   "real",
+	"lire":    "lira",
+	"kronor":  "krona",
+	"kroner":  "krone",
+	"zlotych": "zloty",
+	"yuans":   "yuan",
+}
+
+// VocabularyEntry lists the currencies a word can mean, the most widely
+// traded first.
+type VocabularyEntry struct {
+	Codes []string
+	Kind  string
+}
+
+// Interpretation records words in a query that were read as a currency,
+// such as "$" for USD or "quid" for GBP, or as a date, such as "last
+// Friday".
+type Interpretation struct {
+	Text string `json:"text"`
+	Code string `json:"code,omitempty"`
+	Date string `json:"date,omitempty"`
+	Kind string `json:"kind"`
+	Pos  int    `json:"position"`
+}
+
+func (interpretation Interpretation) String() string {
+	if interpretation.Date != "" {
+		return fmt.Sprintf("%q = %s", interpretation.Text, interpretation.Date)
+	}
+	if interpretation.Kind == VocabularyCorrection {
+		return fmt.Sprintf("%q corrected to %s", interpretation.Text, interpretation.Code)
+	}
+	return fmt.Sprintf("%q = %s", interpretation.Text, interpretation.Code)
+}
+
+// vocabulary maps every phrase a currency can be written as, in lower
+// case, to the currencies it can mean. regionCurrencies maps ISO 3166
+// region codes to the currency a user there means by "$" or "kr".
+var vocabulary, regionCurrencies = loadVocabulary(vocabularyData)
+
+// loadVocabulary reads the embedded symbols, names, slang and regions, and
+// adds the names of current currencies from the ISO 4217 registry.
+func loadVocabulary(data []byte) (map[string]VocabularyEntry, map[string]string) {
+	var file struct {
+		Symbols map[string][]string `json:"symbols"`
+		Names   map[string][]string `json:"names"`
+		Slang   map[string][]string `json:"slang"`
+		Regions map[string]string   `json:"regions"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Fatalf("Failed to parse currency vocabulary: %v", err)
+	}
+
+	entries := make(map[string]VocabularyEntry)
+	for _, currency := range listCurrencies() {
+		name := strings.ToLower(currency.Name)
+		if currency.Status == "historic" || slices.Contains(registryNameStopwords, name) {
+			continue
+		}
+		entries[name] = VocabularyEntry{Codes: []string{currency.Code}, Kind: VocabularyName}
+	}
+
+	sections := map[string]map[string][]string{VocabularySymbol: file.Symbols, VocabularyName: file.Names, VocabularySlang: file.Slang}
+	for kind, section := range sections {
+		for phrase, codes := range section {
+			for _, code := range codes {
+				if _, ok := lookupCurrency(code); !ok {
+					log.Fatalf("Unknown currency %s for %q in currency vocabulary", code, phrase)
+				}
+			}
+			entries[strings.ToLower(phrase)] = VocabularyEntry{Codes: codes, Kind: kind}
+		}
+	}
+
+	for region, code := range file.Regions {
+		if _, ok := lookupCurrency(code); !ok {
+			log.Fatalf("Unknown currency %s for region %s in currency vocabulary", code, region)
+		}
+	}
+
+	return entries, file.Regions
+}
+
+// CurrencyMatch is a run of tokens naming a currency. Candidates holds
+// every currency the words can mean; Code is the one chosen.
+type CurrencyMatch struct {
+	Code       string
+	Candidates []string
+	Kind       string
+	Text       string
+	Pos        int
+	Length     int
+}
+
+func (match CurrencyMatch) interpretation() Interpretation {
+	return Interpretation{Text: match.Text, Code: match.Code, Kind: match.Kind, Pos: match.Pos}
+}
+
+// matchCurrency finds the currency named by the tokens starting at pos,
+// preferring the longest phrase so that "pounds sterling" is read as one.
+func matchCurrency(tokens []Token, pos int) (CurrencyMatch, bool) {
+	token := tokens[pos]
+
+	if token.Kind == TokenSymbol {
+		entry, ok := vocabulary[strings.ToLower(token.Text)]
+		return CurrencyMatch{Code: firstCode(entry), Candidates: entry.Codes, Kind: VocabularySymbol, Text: token.Text, Pos: token.Pos, Length: 1}, ok
+	}
+	if token.Kind != TokenWord {
+		return CurrencyMatch{}, false
+	}
+
+	for length := min(maxPhraseWords, len(tokens)-pos); length > 0; length-- {
+		words := tokens[pos : pos+length]
+		if !allWords(words) {
+			continue
+		}
+
+		if length == 1 {
+			word := token.word()
+			if metal, ok := metalNames[word]; ok {
+				return CurrencyMatch{Code: metal, Candidates: []string{metal}, Kind: VocabularyMetal, Text: token.Text, Pos: token.Pos, Length: 1}, true
+			}
+			if checkValidCurrency(word) {
+				return CurrencyMatch{Code: word, Candidates: []string{word}, Kind: VocabularyCode, Text: token.Text, Pos: token.Pos, Length: 1}, true
+			}
+		}
+
+		for _, phrase := range singularPhrases(words) {
+			if entry, ok := vocabulary[phrase]; ok {
+				text := tokenText(words)
+				return CurrencyMatch{Code: firstCode(entry), Candidates: entry.Codes, Kind: entry.Kind, Text: text, Pos: token.Pos, Length: length}, true
+			}
+		}
+	}
+
+	return CurrencyMatch{}, false
+}
+
+// singularPhrases spells words every way they might be written in the
+// singular, so "pounds sterling" is looked up as "pound sterling".
+func singularPhrases(words []Token) []string {
+	phrases := []string{""}
+	for _, word := range words {
+		var next []string
+		for _, phrase := range phrases {
+			for _, form := range singularForms(strings.ToLower(word.Text)) {
+				next = append(next, strings.TrimSpace(phrase+" "+form))
+			}
+		}
+		phrases = next
+	}
+	return phrases
+}
+
+func singularForms(word string) []string {
+	forms := []string{word}
+	if singular, ok := irregularPlurals[word]; ok {
+		return append(forms, singular)
+	}
+	if stem, ok := strings.CutSuffix(word, "ies"); ok {
+		forms = append(forms, stem+"y")
+	}
+	if stem, ok := strings.CutSuffix(word, "es"); ok {
+		forms = append(forms, stem)
+	}
+	if stem, ok := strings.CutSuffix(word, "s"); ok && len(stem) > 1 {
+		forms = append(forms, stem)
+	}
+	return forms
+}
+
+func allWords(tokens []Token) bool {
+	for _, token := range tokens {
+		if token.Kind != TokenWord {
+			return false
+		}
+	}
+	return true
+}
+
+func tokenText(tokens []Token) string {
+	texts := make([]string, len(tokens))
+	for i, token := range tokens {
+		texts[i] = token.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+func firstCode(entry VocabularyEntry) string {
+	if len(entry.Codes) == 0 {
+		return ""
+	}
+	return entry.Codes[0]
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestVocabularyQueries(t *testing.T) {
+	t.Setenv("LOCALE", "en-US")
+
+	cases := []struct {
+		Input   string
+		Source  string
+		Targets []string
+		Texts   []string
+	}{
+		{"$100 to €", "USD", []string{"EUR"}, []string{"$", "€"}},
+		{"100 dollars in euros", "USD", []string{"EUR"}, []string{"dollars", "euros"}},
+		{"5 quid in loonies", "GBP", []string{"CAD"}, []string{"quid", "loonies"}},
+		{"20 pounds sterling to Japanese yen", "GBP", []string{"JPY"}, []string{"pounds sterling", "Japanese yen"}},
+		{"What is 50 New Zealand dollars in Swiss francs", "NZD", []string{"CHF"}, []string{"New Zealand dollars", "Swiss francs"}},
+		{"₹500 to US$", "INR", []string{"USD"}, []string{"₹", "US$"}},
+		{"1000 ₩ in Swedish kronor", "KRW", []string{"SEK"}, []string{"₩", "Swedish kronor"}},
+		{"100 EUR to USD", "EUR", []string{"USD"}, nil},
+	}
+
+	for _, testCase := range cases {
+		query, err := parseQuery(testCase.Input)
+		if err != nil {
+			t.Errorf("FAILED: parseQuery(%q) returned %v", testCase.Input, err)
+			continue
+		}
+		if query.Source != testCase.Source || !slices.Equal(query.Targets, testCase.Targets) {
+			t.Errorf("FAILED: %q read as %s to %v, expected %s to %v", testCase.Input, query.Source, query.Targets, testCase.Source, testCase.Targets)
+		}
+
+		var texts []string
+		for _, interpretation := range query.Interpretations {
+			texts = append(texts, interpretation.Text)
+		}
+		if !slices.Equal(texts, testCase.Texts) {
+			t.Errorf("FAILED: %q interpreted %v, expected %v", testCase.Input, texts, testCase.Texts)
+		}
+	}
+}
+
+func TestVocabularyStopwords(t *testing.T) {
+	if _, ok := vocabulary["som"]; ok {
+		t.Errorf("FAILED: Expected \"som\" not to name a currency")
+	}
+	if query, err := parseQuery("I won 100 USD to EUR"); err != nil || query.Source != "USD" {
+		t.Errorf("FAILED: Expected \"won\" before an amount and currency to be a word, got %s (%v)", query.Source, err)
+	}
+	if entry := vocabulary["korean won"]; !slices.Equal(entry.Codes, []string{"KRW"}) {
+		t.Errorf("FAILED: Expected \"korean won\" to be KRW, got %v", entry.Codes)
+	}
+	if entry := vocabulary["$"]; firstCode(entry) != "USD" || len(entry.Codes) < 2 {
+		t.Errorf("FAILED: Expected \"$\" to prefer USD among several dollars, got %v", entry.Codes)
+	}
+}
+
+func TestSymbolNames(t *testing.T) {
+	// Every currency written with a symbol can also be named in a word.
+	for symbol, entry := range vocabulary {
+		if entry.Kind != VocabularySymbol {
+			continue
+		}
+		for _, code := range entry.Codes {
+			named := false
+			for phrase, other := range vocabulary {
+				named = named || (other.Kind == VocabularyName && !strings.Contains(phrase, " ") && slices.Contains(other.Codes, code))
+			}
+			if !named {
+				t.Errorf("FAILED: %s is written %q but has no name of one word", code, symbol)
+			}
+		}
+	}
+
+	cases := []struct {
+		Input  string
+		Locale string
+		Source string
+	}{
+		{"100 won to USD", "en-US", "KRW"},
+		{"100 rupees to USD", "en-IN", "INR"},
+		{"100 rupee to USD", "en-IN", "INR"},
+		{"100 yen to USD", "en-US", "JPY"},
+		{"100 yuan to USD", "en-US", "CNY"},
+		{"100 reais to USD", "en-US", "BRL"},
+		{"100 colones to USD", "en-US", "CRC"},
+		{"100 manat to USD", "az-AZ", "AZN"},
+		{"100 shekels to USD", "en-US", "ILS"},
+		{"100 rubles to USD", "en-US", "RUB"},
+		{"100 lira to USD", "en-US", "TRY"},
+		{"100 pesos to USD", "en-PH", "PHP"},
+		{"100 hryvnias to USD", "en-US", "UAH"},
+		{"100 dong to USD", "en-US", "VND"},
+		{"100 naira to USD", "en-US", "NGN"},
+		{"100 baht to USD", "en-US", "THB"},
+		{"100 tenge to USD", "en-US", "KZT"},
+		{"100 guarani to USD", "en-US", "PYG"},
+		{"100 lari to USD", "en-US", "GEL"},
+		{"100 bitcoin to USD", "en-US", "BTC"},
+	}
+
+	rates := make(map[string]float64)
+	for _, testCase := range cases {
+		rates[testCase.Source] = 2
+	}
+	snapshot := newRateSnapshot("USD", rates)
+
+	for _, testCase := range cases {
+		query, err := parseQueryWith(testCase.Input, ParseOptions{Locale: parseLocale(testCase.Locale)})
+		if err != nil || query.Source != testCase.Source {
+			t.Errorf("FAILED: %q in %q read as %s (%v), expected %s", testCase.Input, testCase.Locale, query.Source, err, testCase.Source)
+			continue
+		}
+		if result, err := convert(query.dataInput(), snapshot); err != nil || result.Currency != "USD" {
+			t.Errorf("FAILED: %q converted to %v (%v), expected USD", testCase.Input, result, err)
+		}
+	}
+}
+
+func TestLocaleResolvesSymbols(t *testing.T) {
+	cases := []struct {
+		Input      string
+		Locale     string
+		Source     string
+		Candidates []string
+	}{
+		{"$100 to EUR", "en-US", "USD", nil},
+		{"$100 to EUR", "en_CA.UTF-8", "CAD", nil},
+		{"50 dollars in EUR", "en-AU", "AUD", nil},
+		{"100 kr in EUR", "sv-SE", "SEK", nil},
+		{"100 francs in EUR", "fr-CH", "CHF", nil},
+		{"100 kr in EUR", "en-US", "", []string{"SEK", "NOK", "DKK", "ISK"}},
+		{"$100 to EUR", "", "", []string{"USD", "CAD", "AUD", "NZD", "SGD", "HKD", "MXN"}},
+		{"C$100 to EUR", "en-AU", "CAD", nil},
+	}
+
+	for _, testCase := range cases {
+		query, err := parseQueryWith(testCase.Input, ParseOptions{Locale: parseLocale(testCase.Locale)})
+		var ambiguity *AmbiguityError
+		if errors.As(err, &ambiguity) {
+			if !slices.Equal(ambiguity.Candidates, testCase.Candidates) || ambiguity.Optional {
+				t.Errorf("FAILED: %q in %q asked about %v, expected %v", testCase.Input, testCase.Locale, ambiguity.Candidates, testCase.Candidates)
+			}
+			continue
+		}
+		if err != nil || query.Source != testCase.Source || testCase.Candidates != nil {
+			t.Errorf("FAILED: %q in %q read as %s (%v), expected %s", testCase.Input, testCase.Locale, query.Source, err, testCase.Source)
+		}
+	}
+
+	query, err := parseQueryWith("100 kr in EUR", ParseOptions{Resolutions: map[int]string{4: "NOK"}})
+	if err != nil || query.Source != "NOK" {
+		t.Errorf("FAILED: Expected the answer NOK to settle \"kr\", got %s (%v)", query.Source, err)
+	}
+}